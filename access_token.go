@@ -0,0 +1,280 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+const (
+	// Grant type to exchange a signed JWT assertion for an access token
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// lifetime of the assertion sent to the token endpoint
+	assertionExpiry = time.Hour
+
+	// access tokens are refreshed this long before they expire
+	accessTokenRefreshAhead = 5 * time.Minute
+
+	// lifetime assumed for access tokens without an expires_in
+	defaultAccessTokenExpiry = time.Hour
+
+	// access tokens are kept at least this long, however short their lifetime
+	accessTokenMinRefresh = 1 * time.Minute
+
+	// limit on how long a refresh can take
+	accessTokenFetchTimeout = 30 * time.Second
+)
+
+// DefaultScopes are the OAuth2 scopes requested for access tokens when none
+// are given, covering the Firebase admin REST APIs.
+var DefaultScopes = []string{
+	"https://www.googleapis.com/auth/cloud-platform",
+	"https://www.googleapis.com/auth/firebase",
+	"https://www.googleapis.com/auth/identitytoolkit",
+	"https://www.googleapis.com/auth/userinfo.email",
+}
+
+type (
+	// AccessToken is a Google OAuth2 access token.
+	AccessToken struct {
+		Token  string
+		Expiry time.Time
+
+		// refresh is when a cached token is next refreshed, if it isn't
+		// decided by the expiry
+		refresh time.Time
+	}
+
+	// AccessTokenSource provides the OAuth2 access tokens used to authorize
+	// calls to Google admin REST APIs. Implementations must be safe for
+	// concurrent use.
+	AccessTokenSource interface {
+		AccessToken(ctx context.Context) (*AccessToken, error)
+	}
+
+	// serviceAccountTokenSource exchanges an assertion signed with the
+	// service account private key for access tokens.
+	serviceAccountTokenSource struct {
+		cache  tokenCache
+		creds  *Credentials
+		scopes []string
+	}
+
+	// tokenCache holds the current access token of a source, and the
+	// refresh in progress that concurrent callers share.
+	tokenCache struct {
+		mu    sync.Mutex
+		token *AccessToken
+		fetch *tokenFetch
+	}
+
+	// tokenFetch is a refresh shared by concurrent callers, which wait for
+	// done to be closed.
+	tokenFetch struct {
+		done  chan struct{}
+		token *AccessToken
+		err   error
+	}
+)
+
+// NewAccessTokenSource returns an AccessTokenSource for the service account
+// credentials, which must have a private key. Tokens are cached until
+// shortly before they expire. The DefaultScopes are requested if no scopes
+// are given.
+func NewAccessTokenSource(creds *Credentials, scopes ...string) AccessTokenSource {
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	return &serviceAccountTokenSource{
+		creds:  creds,
+		scopes: scopes,
+	}
+}
+
+// AccessToken implements AccessTokenSource. Concurrent callers share a single
+// refresh.
+func (s *serviceAccountTokenSource) AccessToken(ctx context.Context) (*AccessToken, error) {
+	return s.cache.get(ctx, s.exchange)
+}
+
+// exchange gets a new access token for the service account.
+func (s *serviceAccountTokenSource) exchange(ctx context.Context) (*AccessToken, error) {
+	if s.creds.PrivateKey == nil {
+		return nil, errors.New("Must provide credentials with a private key.")
+	}
+
+	assertion, err := s.assertion()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type": {jwtBearerGrantType},
+		"assertion":  {assertion},
+	}
+	return exchangeToken(ctx, s.tokenURI(), form)
+}
+
+// assertion returns the JWT asserting the service account identity to the
+// token endpoint.
+func (s *serviceAccountTokenSource) assertion() (string, error) {
+	now := clock.Now()
+	claims := jws.Claims{}
+	claims.SetIssuer(s.creds.ClientEmail)
+	claims.SetAudience(s.tokenURI())
+	claims.SetIssuedAt(now)
+	claims.SetExpiration(now.Add(assertionExpiry))
+	claims.Set("scope", strings.Join(s.scopes, " "))
+
+	jwt := jws.NewJWT(claims, crypto.SigningMethodRS256)
+	if s.creds.PrivateKeyID != "" {
+		jwt.(jws.JWS).Protected().Set("kid", s.creds.PrivateKeyID)
+	}
+	b, err := jwt.Serialize(s.creds.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s *serviceAccountTokenSource) tokenURI() string {
+	if s.creds.TokenURI != "" {
+		return s.creds.TokenURI
+	}
+	return defaultTokenURI
+}
+
+// get returns the cached token if it's valid, or else waits for a refresh
+// with fn, starting one unless another caller already has.
+func (c *tokenCache) get(ctx context.Context, fn func(context.Context) (*AccessToken, error)) (*AccessToken, error) {
+	c.mu.Lock()
+	if c.token.valid() {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	f := c.fetch
+	if f == nil {
+		f = &tokenFetch{done: make(chan struct{})}
+		c.fetch = f
+		go c.refresh(ctx, f, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh gets a new token with fn, storing the result in the cache and the
+// fetch that waiting callers share.
+func (c *tokenCache) refresh(ctx context.Context, f *tokenFetch, fn func(context.Context) (*AccessToken, error)) {
+	// the refresh outlives a caller that gives up waiting, unless its
+	// client only works for the caller's request
+	if !requestScopedClient(ctx) {
+		ctx = detach(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, accessTokenFetchTimeout)
+	defer cancel()
+
+	token, err := fn(ctx)
+
+	c.mu.Lock()
+	if err == nil {
+		c.token = token
+	}
+	f.token, f.err = token, err
+	c.fetch = nil
+	c.mu.Unlock()
+
+	close(f.done)
+}
+
+// valid reports whether the token can be used without refreshing it.
+func (t *AccessToken) valid() bool {
+	if t == nil || t.Token == "" {
+		return false
+	}
+	if !t.refresh.IsZero() {
+		return clock.Now().Before(t.refresh)
+	}
+	return clock.Now().Add(accessTokenRefreshAhead).Before(t.Expiry)
+}
+
+// exchangeToken posts the form to an OAuth2 token endpoint and returns the
+// access token from the response.
+func exchangeToken(ctx context.Context, endpoint string, form url.Values) (*AccessToken, error) {
+	client, err := ContextClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeAccessToken(resp)
+}
+
+// decodeAccessToken decodes an OAuth2 token response. A token without an
+// expires_in is assumed to last an hour, and short-lived tokens are still
+// cached for a minimum time so they aren't exchanged on every call.
+func decodeAccessToken(resp *http.Response) (*AccessToken, error) {
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		if body.Error == "" {
+			body.Error = resp.Status
+		}
+		return nil, fmt.Errorf("access token fails: %s", strings.TrimSpace(body.Error+" "+body.ErrorDescription))
+	}
+
+	lifetime := time.Duration(body.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultAccessTokenExpiry
+	}
+	ahead := accessTokenRefreshAhead
+	if ahead > lifetime/2 {
+		ahead = lifetime / 2
+	}
+	refresh := lifetime - ahead
+	if refresh < accessTokenMinRefresh {
+		refresh = accessTokenMinRefresh
+	}
+
+	now := clock.Now()
+	return &AccessToken{
+		Token:   body.AccessToken,
+		Expiry:  now.Add(lifetime),
+		refresh: now.Add(refresh),
+	}, nil
+}
@@ -0,0 +1,161 @@
+package firebase
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+func TestAccessTokenSource(t *testing.T) {
+	var exchanges int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != jwtBearerGrantType {
+			t.Errorf("unexpected grant type %s", r.FormValue("grant_type"))
+		}
+		assertion, err := jws.ParseJWT([]byte(r.FormValue("assertion")))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := assertion.Validate(&testKey.PublicKey, crypto.SigningMethodRS256); err != nil {
+			t.Error(err)
+		}
+		if scope := assertion.Claims().Get("scope"); scope != "scope-a scope-b" {
+			t.Errorf("unexpected scope %v", scope)
+		}
+		atomic.AddInt32(&exchanges, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer tokenSrv.Close()
+
+	creds := &Credentials{ClientEmail: testClientEmail, PrivateKey: testKey, PrivateKeyID: testKeyID, TokenURI: tokenSrv.URL}
+	source := NewAccessTokenSource(creds, "scope-a", "scope-b")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := source.AccessToken(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if token.Token != "access-token" || token.Expiry.Before(time.Now().Add(59*time.Minute)) {
+				t.Errorf("unexpected token %+v", token)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&exchanges); n != 1 {
+		t.Errorf("expected 1 exchange, got %d", n)
+	}
+
+	// admin REST calls are authorized with the token
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer access-token" {
+			t.Errorf("unexpected authorization %q", auth)
+		}
+		w.Write([]byte(`{"sessionCookie":"cookie"}`))
+	}))
+	defer apiSrv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, identityToolkitURL: apiSrv.URL, tokens: source}
+	if _, err := app.Auth().CreateSessionCookie(context.Background(), "id-token", time.Hour); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccessTokenExpiry(t *testing.T) {
+	var exchanges int32
+	var expiresIn int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&exchanges, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if n := atomic.LoadInt32(&expiresIn); n != 0 {
+			fmt.Fprintf(w, `{"access_token":"access-token","expires_in":%d}`, n)
+			return
+		}
+		w.Write([]byte(`{"access_token":"access-token"}`))
+	}))
+	defer tokenSrv.Close()
+
+	start := time.Now()
+	clock = mockClock(start)
+	defer func() { clock = realClock{} }()
+
+	get := func(source AccessTokenSource, at time.Duration) *AccessToken {
+		clock = mockClock(start.Add(at))
+		token, err := source.AccessToken(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	creds := &Credentials{ClientEmail: testClientEmail, PrivateKey: testKey, TokenURI: tokenSrv.URL}
+
+	// a token without expires_in lasts an hour
+	source := NewAccessTokenSource(creds)
+	if token := get(source, 0); !token.Expiry.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected expiry in an hour, got %v", token.Expiry.Sub(start))
+	}
+	get(source, 30*time.Minute)
+	if n := atomic.LoadInt32(&exchanges); n != 1 {
+		t.Errorf("expected 1 exchange, got %d", n)
+	}
+
+	// a short-lived token is still kept for the minimum time
+	atomic.StoreInt32(&exchanges, 0)
+	atomic.StoreInt32(&expiresIn, 1)
+	source = NewAccessTokenSource(creds)
+	for i := 0; i < 10; i++ {
+		get(source, time.Duration(i)*time.Second)
+	}
+	if n := atomic.LoadInt32(&exchanges); n != 1 {
+		t.Errorf("expected 1 exchange, got %d", n)
+	}
+	get(source, 2*time.Minute)
+	if n := atomic.LoadInt32(&exchanges); n != 2 {
+		t.Errorf("expected 2 exchanges, got %d", n)
+	}
+}
+
+func TestAccessTokenSourceDeadline(t *testing.T) {
+	release := make(chan struct{})
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-token","expires_in":3600}`))
+	}))
+	defer tokenSrv.Close()
+
+	source := NewAccessTokenSource(&Credentials{ClientEmail: testClientEmail, PrivateKey: testKey, TokenURI: tokenSrv.URL})
+
+	slow := make(chan error, 1)
+	go func() {
+		_, err := source.AccessToken(context.Background())
+		slow <- err
+	}()
+
+	// a caller waiting on the slow refresh still gives up at its deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := source.AccessToken(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	close(release)
+	if err := <-slow; err != nil {
+		t.Error(err)
+	}
+}
@@ -6,6 +6,8 @@ package firebase
 
 import (
 	"net/http"
+	"os"
+	"strings"
 
 	"golang.org/x/net/context"
 	"google.golang.org/appengine"
@@ -15,12 +17,51 @@ import (
 func init() {
 	RegisterRequestContextFunc(requestContextAppEngine)
 	RegisterContextClientFunc(contextClientAppEngine)
+	RegisterContextSignerFunc(contextSignerAppEngine)
+	platformCredentials = credentialsAppEngine
 }
 
 func requestContextAppEngine(req *http.Request) (context.Context, error) {
 	return appengine.NewContext(req), nil
 }
 
+// credentialsAppEngine returns the project of the App Engine app, which has
+// no metadata server. Custom tokens are signed with the app identity API.
+func credentialsAppEngine() *Credentials {
+	// the application ID has a partition prefix, e.g. "s~project-id"
+	projectID := os.Getenv("APPLICATION_ID")
+	if i := strings.Index(projectID, "~"); i >= 0 {
+		projectID = projectID[i+1:]
+	}
+	if projectID == "" {
+		return nil
+	}
+	return &Credentials{ProjectID: projectID}
+}
+
 func contextClientAppEngine(ctx context.Context) (*http.Client, error) {
 	return urlfetch.Client(ctx), nil
 }
+
+// appengineSigner signs with the app's default service account using the
+// App Engine app identity API, so no private key is needed.
+type appengineSigner struct {
+	email string
+}
+
+func contextSignerAppEngine(ctx context.Context) (Signer, error) {
+	email, err := appengine.ServiceAccount(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &appengineSigner{email: email}, nil
+}
+
+func (s *appengineSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	_, signature, err := appengine.SignBytes(ctx, data)
+	return signature, err
+}
+
+func (s *appengineSigner) Email() string {
+	return s.email
+}
@@ -0,0 +1,72 @@
+// +build appengine
+
+package firebase
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/aetest"
+)
+
+func TestAppEngineSigner(t *testing.T) {
+	ctx, done, err := aetest.NewContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done()
+
+	// without a key file the app is built from the App Engine project
+	defer setenv(map[string]string{
+		"APPLICATION_ID": "s~" + testProjectID,
+		credentialsEnv:   "",
+		gcloudConfigEnv:  "missing",
+	})()
+
+	app, err := NewApp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != testProjectID {
+		t.Errorf("expected project %s, got %s", testProjectID, app.creds.ProjectID)
+	}
+
+	s, err := app.Auth().CreateCustomToken(ctx, "uid1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := jws.ParseJWT([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email, err := appengine.ServiceAccount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if iss, _ := token.Claims().Issuer(); iss != email {
+		t.Errorf("expected issuer %s, got %s", email, iss)
+	}
+
+	certs, err := appengine.PublicCertificates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range certs {
+		block, _ := pem.Decode(c.Data)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Validate(cert.PublicKey, crypto.SigningMethodRS256) == nil {
+			return
+		}
+	}
+	t.Error("token not signed by any of the app's certificates")
+}
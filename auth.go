@@ -20,6 +20,14 @@ type (
 	}
 
 	Auth struct {
-		app *App
+		app      *App
+		tenantID string
+		verify   verifyConfig
 	}
 )
+
+// TenantID returns the Identity Platform tenant the Auth is scoped to, or an
+// empty string for the project-level Auth.
+func (a *Auth) TenantID() string {
+	return a.tenantID
+}
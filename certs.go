@@ -19,37 +19,182 @@ import (
 const (
 	defaultCertsCacheTime = 1 * time.Hour
 
+	// keys are cached at least this long, however short their max-age
+	certsMinCacheTime = 1 * time.Minute
+
+	// keys are refreshed in the background this long before they expire
+	certsRefreshAhead = 5 * time.Minute
+
+	// expired keys keep being served for this long while refreshes fail
+	certsMaxStale = 24 * time.Hour
+
+	// wait between failed background refreshes
+	certsRetryInterval = 30 * time.Second
+
+	// limit on how long a download can take
+	certsFetchTimeout = 30 * time.Second
+
+	// minimum time between downloads forced by an unknown key ID
+	defaultCertsRefreshInterval = 1 * time.Minute
+
+	// unknown key IDs are remembered for this long
+	defaultCertsNegativeCacheTime = 30 * time.Second
+
+	// limit on the number of unknown key IDs remembered
+	certsMaxMissing = 1000
+
 	// URL containing the public keys for the Google certs
 	clientCertURL = "https://www.googleapis.com/robot/v1/metadata/x509/securetoken@system.gserviceaccount.com"
+
+	// URL containing the public keys for session cookies
+	sessionCookieCertURL = "https://www.googleapis.com/identitytoolkit/v3/relyingparty/publicKeys"
 )
 
 type (
+	// certificateStore caches the keys downloaded from a URL. The keys are
+	// *x509.Certificate values for the x509 metadata format or public keys
+	// for JWKS.
 	certificateStore struct {
 		sync.RWMutex
 		url   string
-		certs map[string]*x509.Certificate
+		parse parseFunc
+		keys  map[string]interface{}
 		exp   time.Time
+
+		// refresh is when the keys are due to be refreshed in the background
+		refresh time.Time
+
+		// fetch is the download in progress, shared by all callers
+		fetch *certificateFetch
+		// retry is when a background refresh may be tried after a failure
+		retry time.Time
+
+		// refreshInterval limits how often an unknown key ID can force a
+		// download, counting from when the last download of any kind started
+		refreshInterval time.Duration
+		fetched         time.Time
+
+		// missing remembers unknown key IDs until the time they expire
+		negativeCacheTime time.Duration
+		missing           map[string]time.Time
+
+		// closed stops any more downloads
+		closed bool
 	}
-)
 
-var (
-	certs *certificateStore
-)
+	// KeySourceOption configures the caching of a downloaded key source
+	KeySourceOption func(*certificateStore)
 
-func init() {
-	certs = newCertificateStore("")
-}
+	certificateFetch struct {
+		done   chan struct{}
+		err    error
+		cancel context.CancelFunc
+	}
 
-func newCertificateStore(url string) *certificateStore {
+	// parseFunc parses the keys in a download response.
+	parseFunc func(io.Reader) (map[string]interface{}, error)
+)
+
+func newCertificateStore(url string, options ...KeySourceOption) *certificateStore {
 	if url == "" {
 		url = clientCertURL
 	}
-	return &certificateStore{
-		url: url,
+	return newKeyStore(url, parse, options...)
+}
+
+func newKeyStore(url string, parse parseFunc, options ...KeySourceOption) *certificateStore {
+	c := &certificateStore{
+		url:               url,
+		parse:             parse,
+		refreshInterval:   defaultCertsRefreshInterval,
+		negativeCacheTime: defaultCertsNegativeCacheTime,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// KeySourceRefreshInterval sets the minimum time between downloads forced by
+// a token with an unknown key ID
+func KeySourceRefreshInterval(interval time.Duration) KeySourceOption {
+	return func(c *certificateStore) {
+		c.refreshInterval = interval
+	}
+}
+
+// KeySourceNegativeCacheTime sets how long an unknown key ID is remembered
+// before it can force another download
+func KeySourceNegativeCacheTime(d time.Duration) KeySourceOption {
+	return func(c *certificateStore) {
+		c.negativeCacheTime = d
 	}
 }
 
+// Get returns the certificate for the key ID from an x509 store.
 func (c *certificateStore) Get(ctx context.Context, kid string) (*x509.Certificate, error) {
+	key, err := c.get(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, ok := key.(*x509.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("key ID %s is not a certificate", kid)
+	}
+	return cert, nil
+}
+
+// PublicKey implements KeySource.
+func (c *certificateStore) PublicKey(ctx context.Context, kid string) (interface{}, error) {
+	key, err := c.get(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+	return publicKey(key), nil
+}
+
+// get returns the key for the key ID. An unknown key ID may mean the keys
+// were rotated before the cached set expired, so it forces one download,
+// rate limited by the refresh interval. Key IDs that are still unknown are
+// remembered for the negative cache time so that tokens with random key IDs
+// can't be used to hammer the key server.
+func (c *certificateStore) get(ctx context.Context, kid string) (interface{}, error) {
+	if err := c.ensureLoaded(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, found := c.lookup(kid); found {
+		return key, nil
+	}
+
+	if !c.allowForcedFetch(kid) {
+		return nil, &KeyNotFoundError{KeyID: kid}
+	}
+
+	if err := c.wait(ctx, c.startFetch(ctx)); err != nil {
+		return nil, err
+	}
+
+	if key, found := c.lookup(kid); found {
+		return key, nil
+	}
+
+	c.Lock()
+	if len(c.missing) >= certsMaxMissing {
+		c.missing = nil
+	}
+	if c.missing == nil {
+		c.missing = make(map[string]time.Time)
+	}
+	c.missing[kid] = clock.Now().Add(c.negativeCacheTime)
+	c.Unlock()
+
+	return nil, &KeyNotFoundError{KeyID: kid}
+}
+
+// publicKeys returns all the public keys in the store.
+func (c *certificateStore) publicKeys(ctx context.Context) ([]interface{}, error) {
 	if err := c.ensureLoaded(ctx); err != nil {
 		return nil, err
 	}
@@ -57,53 +202,177 @@ func (c *certificateStore) Get(ctx context.Context, kid string) (*x509.Certifica
 	c.RLock()
 	defer c.RUnlock()
 
-	cert, found := c.certs[kid]
-	if !found {
-		return nil, fmt.Errorf("certificate not found for key ID: %s", kid)
+	keys := make([]interface{}, 0, len(c.keys))
+	for _, key := range c.keys {
+		keys = append(keys, publicKey(key))
 	}
-	return cert, nil
+	return keys, nil
+}
+
+func (c *certificateStore) lookup(kid string) (interface{}, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	key, found := c.keys[kid]
+	return key, found
+}
+
+// allowForcedFetch reports whether an unknown key ID can force a download,
+// recording the attempt if so.
+func (c *certificateStore) allowForcedFetch(kid string) bool {
+	now := clock.Now()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if exp, ok := c.missing[kid]; ok && now.Before(exp) {
+		return false
+	}
+	if now.Before(c.fetched.Add(c.refreshInterval)) {
+		return false
+	}
+	c.fetched = now
+	return true
 }
 
+// ensureLoaded makes sure there are keys to serve. Fresh keys are served
+// as-is. Keys close to expiry, or expired less than certsMaxStale ago, are
+// served while a refresh runs in the background. Otherwise the caller waits
+// for the download, which is shared with any other callers waiting on it.
 func (c *certificateStore) ensureLoaded(ctx context.Context) error {
+	now := clock.Now()
+
 	c.RLock()
-	if c.exp.After(clock.Now()) {
-		c.RUnlock()
+	loaded, exp, refresh, retry := c.keys != nil, c.exp, c.refresh, c.retry
+	c.RUnlock()
+
+	switch {
+	case now.Before(refresh):
+		return nil
+	case loaded && now.Before(exp.Add(certsMaxStale)):
+		if !now.Before(retry) {
+			f := c.startFetch(ctx)
+			// a request-scoped client can't refresh in the background, so
+			// the caller waits, and gets the stale keys if the refresh fails
+			if requestScopedClient(ctx) {
+				c.wait(ctx, f)
+			}
+		}
 		return nil
 	}
-	c.RUnlock()
 
-	certs, cacheTime, err := c.download(ctx)
-	if err != nil {
-		return err
+	return c.wait(ctx, c.startFetch(ctx))
+}
+
+// wait waits for the fetch to complete or the context to be done.
+func (c *certificateStore) wait(ctx context.Context, f *certificateFetch) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return &CertFetchError{URL: c.url, Err: ctx.Err()}
 	}
+}
 
+// startFetch starts downloading the keys, unless a download is already in
+// progress, and returns the fetch to wait on. The download is detached from
+// the cancellation of ctx so that one caller giving up doesn't fail the
+// others.
+func (c *certificateStore) startFetch(ctx context.Context) *certificateFetch {
 	c.Lock()
 	defer c.Unlock()
-	c.certs = certs
-	c.exp = clock.Now().Add(cacheTime)
-	return nil
+
+	if c.fetch != nil {
+		return c.fetch
+	}
+
+	if c.closed {
+		f := &certificateFetch{done: make(chan struct{}), err: &CertFetchError{URL: c.url, Err: ErrAppDeleted}}
+		close(f.done)
+		return f
+	}
+
+	// the download outlives a caller that gives up waiting, unless its
+	// client only works for the caller's request
+	if !requestScopedClient(ctx) {
+		ctx = detach(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, certsFetchTimeout)
+	f := &certificateFetch{done: make(chan struct{}), cancel: cancel}
+	c.fetch = f
+	c.fetched = clock.Now()
+
+	go func() {
+		defer cancel()
+
+		keys, cacheTime, err := c.download(ctx)
+
+		c.Lock()
+		if err == nil {
+			if cacheTime < certsMinCacheTime {
+				cacheTime = certsMinCacheTime
+			}
+			if cacheTime < c.refreshInterval {
+				cacheTime = c.refreshInterval
+			}
+			ahead := certsRefreshAhead
+			if ahead > cacheTime/2 {
+				ahead = cacheTime / 2
+			}
+			now := clock.Now()
+			c.keys = keys
+			c.exp = now.Add(cacheTime)
+			c.refresh = c.exp.Add(-ahead)
+			c.missing = nil
+		} else {
+			c.retry = clock.Now().Add(certsRetryInterval)
+		}
+		f.err = err
+		c.fetch = nil
+		c.Unlock()
+
+		close(f.done)
+	}()
+
+	return f
 }
 
-// TODO: pass in transport, provide appengine stub to automatically get it from context
-func (c *certificateStore) download(ctx context.Context) (map[string]*x509.Certificate, time.Duration, error) {
+// close cancels any download in progress and stops further downloads.
+func (c *certificateStore) close() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.closed = true
+	if c.fetch != nil {
+		c.fetch.cancel()
+	}
+}
+
+// download fetches and parses the keys using the client from ContextClient.
+func (c *certificateStore) download(ctx context.Context) (map[string]interface{}, time.Duration, error) {
 	client, err := ContextClient(ctx)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &CertFetchError{URL: c.url, Err: err}
+	}
+
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return nil, 0, &CertFetchError{URL: c.url, Err: err}
 	}
 
-	resp, err := client.Get(c.url)
+	resp, err := client.Do(req.WithContext(ctx))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &CertFetchError{URL: c.url, Err: err}
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, 0, fmt.Errorf("download %s fails: %s", c.url, resp.Status)
+		return nil, 0, &CertFetchError{URL: c.url, StatusCode: resp.StatusCode}
 	}
-	certs, err := parse(resp.Body)
+	keys, err := c.parse(resp.Body)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, &CertFetchError{URL: c.url, Err: err}
 	}
-	return certs, cacheTime(resp), nil
+	return keys, cacheTime(resp), nil
 }
 
 // parse parses the certificates response in JSON format.
@@ -112,15 +381,18 @@ func (c *certificateStore) download(ctx context.Context) (map[string]*x509.Certi
 //   "kid1": "-----BEGIN CERTIFICATE-----...-----END CERTIFICATE-----",
 //   "kid2": "-----BEGIN CERTIFICATE-----...-----END CERTIFICATE-----",
 // }
-func parse(r io.Reader) (map[string]*x509.Certificate, error) {
+func parse(r io.Reader) (map[string]interface{}, error) {
 	m := make(map[string]string)
 	dec := json.NewDecoder(r)
 	if err := dec.Decode(&m); err != nil {
 		return nil, err
 	}
-	certs := make(map[string]*x509.Certificate)
+	certs := make(map[string]interface{})
 	for k, v := range m {
 		block, _ := pem.Decode([]byte(v))
+		if block == nil {
+			return nil, fmt.Errorf("certificate for key ID %s is not PEM encoded", k)
+		}
 		c, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
 			return nil, err
@@ -1,9 +1,16 @@
 package firebase
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"golang.org/x/net/context"
 	"google.golang.org/appengine/aetest"
 )
 
@@ -22,9 +29,8 @@ func TestCertificateStore(t *testing.T) {
 	}
 	t.Logf("cert 1 %v", cert.AuthorityKeyId)
 
-	nowFunc = func() time.Time {
-		return time.Date(2000, 12, 15, 17, 8, 00, 0, time.UTC)
-	}
+	clock = mockClock(time.Date(2000, 12, 15, 17, 8, 00, 0, time.UTC))
+	defer func() { clock = realClock{} }()
 
 	cert, err = store.Get(ctx, "09712c9531f921fce0118dba9441de0ed4f408f7")
 	if err != nil {
@@ -32,3 +38,276 @@ func TestCertificateStore(t *testing.T) {
 	}
 	t.Logf("cert 2 %v", cert.AuthorityKeyId)
 }
+
+// mockClock reports a fixed time.
+type mockClock time.Time
+
+func (c mockClock) Now() time.Time { return time.Time(c) }
+
+func TestCertificateStoreCoalesce(t *testing.T) {
+	body := testCertBody(t)
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	store := newCertificateStore(srv.URL)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.PublicKey(ctx, testKeyID); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("expected 1 download, got %d", atomic.LoadInt32(&hits))
+	}
+}
+
+func TestCertificateStoreRefresh(t *testing.T) {
+	body := testCertBody(t)
+	var hits int32
+	var failing int32
+	fetched := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		defer func() { fetched <- struct{}{} }()
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	clock = mockClock(start)
+	defer func() { clock = realClock{} }()
+
+	store := newCertificateStore(srv.URL)
+	ctx := context.Background()
+
+	get := func(at time.Duration) error {
+		clock = mockClock(start.Add(at))
+		_, err := store.PublicKey(ctx, testKeyID)
+		return err
+	}
+	wait := func() {
+		select {
+		case <-fetched:
+		case <-time.After(time.Second):
+			t.Fatal("expected a background download")
+		}
+		// let the download goroutine store the result
+		for {
+			store.RLock()
+			done := store.fetch == nil
+			store.RUnlock()
+			if done {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	if err := get(0); err != nil {
+		t.Fatal(err)
+	}
+	<-fetched
+
+	// fresh keys are served from the cache
+	if err := get(30 * time.Minute); err != nil || atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected cached keys, got %v after %d downloads", err, atomic.LoadInt32(&hits))
+	}
+
+	// keys about to expire are refreshed in the background
+	if err := get(58 * time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("expected background refresh, got %d downloads", atomic.LoadInt32(&hits))
+	}
+
+	// expired keys are still served when the refresh fails
+	atomic.StoreInt32(&failing, 1)
+	if err := get(3 * time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if err := get(3*time.Hour + time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected no retry before the retry interval, got %d downloads", atomic.LoadInt32(&hits))
+	}
+
+	// but not forever
+	if err := get(48 * time.Hour); err == nil {
+		t.Fatal("expected error once the keys are too stale")
+	}
+}
+
+func TestCertificateStoreNoMaxAge(t *testing.T) {
+	body := testCertBody(t)
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "public, max-age=0")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	clock = mockClock(start)
+	defer func() { clock = realClock{} }()
+
+	store := newCertificateStore(srv.URL)
+	ctx := context.Background()
+
+	// a burst of calls is served from a single download
+	for i := 0; i < 10; i++ {
+		clock = mockClock(start.Add(time.Duration(i) * time.Second))
+		if _, err := store.PublicKey(ctx, testKeyID); err != nil {
+			t.Fatal(err)
+		}
+		// let any background download finish
+		for {
+			store.RLock()
+			done := store.fetch == nil
+			store.RUnlock()
+			if done {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("expected 1 download, got %d", n)
+	}
+}
+
+func TestCertificateStoreRequestScopedClient(t *testing.T) {
+	body := testCertBody(t)
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) > 1 {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	// a registered client, like App Engine's urlfetch, is tied to a request
+	type requestKey struct{}
+	saved := contextClientFuncs
+	defer func() { contextClientFuncs = saved }()
+	RegisterContextClientFunc(func(ctx context.Context) (*http.Client, error) {
+		if ctx.Value(requestKey{}) != nil {
+			return srv.Client(), nil
+		}
+		return nil, nil
+	})
+
+	start := time.Now()
+	clock = mockClock(start)
+	defer func() { clock = realClock{} }()
+
+	store := newCertificateStore(srv.URL)
+	ctx := context.WithValue(context.Background(), requestKey{}, true)
+	if _, err := store.PublicKey(ctx, testKeyID); err != nil {
+		t.Fatal(err)
+	}
+
+	// keys about to expire are refreshed before the caller returns
+	clock = mockClock(start.Add(58 * time.Minute))
+	if _, err := store.PublicKey(ctx, testKeyID); err != nil {
+		t.Fatal(err)
+	}
+	store.RLock()
+	done := store.fetch == nil
+	store.RUnlock()
+	if n := atomic.LoadInt32(&hits); n != 2 || !done {
+		t.Errorf("expected the refresh to finish before returning, got %d downloads", n)
+	}
+}
+
+func TestCertificateStoreUnknownKey(t *testing.T) {
+	var current map[string]string
+	json.Unmarshal(testCertBody(t), &current)
+	var hits int32
+	var rotated int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		keys := map[string]string{testKeyID: current[testKeyID]}
+		if atomic.LoadInt32(&rotated) == 1 {
+			keys["rotated"] = current[testKeyID]
+		}
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(keys)
+	}))
+	defer srv.Close()
+
+	start := time.Now()
+	clock = mockClock(start)
+	defer func() { clock = realClock{} }()
+
+	store := newCertificateStore(srv.URL,
+		KeySourceRefreshInterval(time.Minute),
+		KeySourceNegativeCacheTime(5*time.Minute))
+	ctx := context.Background()
+
+	get := func(at time.Duration, kid string) error {
+		clock = mockClock(start.Add(at))
+		_, err := store.PublicKey(ctx, kid)
+		return err
+	}
+
+	if err := get(0, testKeyID); err != nil {
+		t.Fatal(err)
+	}
+
+	// an unknown key right after a download doesn't force another
+	if err := get(time.Second, "rotated"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("expected 1 download, got %d", n)
+	}
+
+	// once the interval has passed a rotated key is picked up
+	atomic.StoreInt32(&rotated, 1)
+	if err := get(2*time.Minute, "rotated"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("expected 2 downloads, got %d", n)
+	}
+
+	// a key that is still unknown is remembered
+	if err := get(4*time.Minute, "random"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if err := get(6*time.Minute, "random"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound, got %v", err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Fatalf("expected 3 downloads, got %d", n)
+	}
+}
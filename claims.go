@@ -1,6 +1,80 @@
 package firebase
 
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// Firebase rejects developer claims larger than this when serialized
+	maxDeveloperClaimsSize = 1000
+)
+
 // Claims to be stored in a custom token (and made available to security rules
 // in Database, Storage, etc.).  These must be serializable to JSON
 // (e.g. contains only Maps, Arrays, Strings, Booleans, Numbers, etc.).
 type Claims map[string]interface{}
+
+// ClaimsError lists every problem found by Claims.Validate.
+type ClaimsError struct {
+	// Reserved are the keys that are reserved for use by Firebase.
+	Reserved []string
+	// Unserializable are the keys with values that can't be encoded as JSON.
+	Unserializable []string
+	// Size is the serialized size of the claims when over the limit.
+	Size int
+}
+
+func (e *ClaimsError) Error() string {
+	var problems []string
+	if len(e.Reserved) > 0 {
+		problems = append(problems, "reserved keys: "+strings.Join(e.Reserved, ", "))
+	}
+	if len(e.Unserializable) > 0 {
+		problems = append(problems, "keys not serializable to JSON: "+strings.Join(e.Unserializable, ", "))
+	}
+	if e.Size > 0 {
+		problems = append(problems, fmt.Sprintf("%d bytes exceeds the %d byte limit", e.Size, maxDeveloperClaimsSize))
+	}
+	return "developer claims are invalid: " + strings.Join(problems, "; ")
+}
+
+// Validate checks the claims will be accepted by Firebase: no top-level key
+// may be a reserved name, every value must be serializable to JSON and the
+// serialized claims must be no more than 1000 bytes. All the problems found
+// are reported in a *ClaimsError.
+func (c Claims) Validate() error {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	e := &ClaimsError{}
+	valid := make(map[string]interface{}, len(c))
+	for _, key := range keys {
+		if isReserved(key) {
+			e.Reserved = append(e.Reserved, key)
+		}
+		if _, err := json.Marshal(c[key]); err != nil {
+			e.Unserializable = append(e.Unserializable, key)
+			continue
+		}
+		valid[key] = c[key]
+	}
+
+	b, err := json.Marshal(valid)
+	if err != nil {
+		return err
+	}
+	if len(b) > maxDeveloperClaimsSize {
+		e.Size = len(b)
+	}
+
+	if len(e.Reserved) > 0 || len(e.Unserializable) > 0 || e.Size > 0 {
+		return e
+	}
+	return nil
+}
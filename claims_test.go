@@ -0,0 +1,37 @@
+package firebase
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClaimsValidate(t *testing.T) {
+	if err := (Claims{"roles": []string{"admin"}, "level": 3}).Validate(); err != nil {
+		t.Error(err)
+	}
+
+	claims := Claims{
+		"sub":    "other",
+		"iss":    "other",
+		"fn":     func() {},
+		"ch":     make(chan int),
+		"padded": strings.Repeat("x", maxDeveloperClaimsSize),
+	}
+	err := claims.Validate()
+
+	var claimsErr *ClaimsError
+	if !errors.As(err, &claimsErr) {
+		t.Fatalf("expected ClaimsError, got %v", err)
+	}
+	if !reflect.DeepEqual(claimsErr.Reserved, []string{"iss", "sub"}) {
+		t.Errorf("expected reserved keys iss, sub, got %v", claimsErr.Reserved)
+	}
+	if !reflect.DeepEqual(claimsErr.Unserializable, []string{"ch", "fn"}) {
+		t.Errorf("expected unserializable keys ch, fn, got %v", claimsErr.Unserializable)
+	}
+	if claimsErr.Size <= maxDeveloperClaimsSize {
+		t.Errorf("expected size over the limit, got %d", claimsErr.Size)
+	}
+}
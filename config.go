@@ -1,11 +1,46 @@
 package firebase
 
+import (
+	"net/http"
+	"strings"
+)
+
 type (
 	// Config stores firebase app configuration settings
 	Config struct {
 		Name            string
 		Credentials     *Credentials
 		CredentialsPath string
+
+		// MetadataCredentials reads the credentials from the metadata server
+		MetadataCredentials bool
+
+		// ProjectID overrides the project ID of the credentials
+		ProjectID string
+
+		// IdentityToolkitURL is the base URL for Identity Toolkit REST calls
+		IdentityToolkitURL string
+		// SecureTokenURL is the base URL for exchanging refresh tokens
+		SecureTokenURL string
+		// APIKey is the Web API key sent with Identity Toolkit REST calls
+		APIKey string
+		// AuthEmulatorHost is the host:port of the Firebase Auth Emulator
+		AuthEmulatorHost string
+
+		// KeySource provides the public keys to verify ID tokens
+		KeySource KeySource
+		// SessionKeySource provides the public keys to verify session cookies
+		SessionKeySource KeySource
+
+		// Signer signs custom tokens
+		Signer Signer
+
+		// AccessTokenSource authorizes calls to the admin REST APIs
+		AccessTokenSource AccessTokenSource
+
+		// HTTPClient makes the app's outbound requests, unless the context
+		// provides a client
+		HTTPClient *http.Client
 	}
 
 	// Option is the signature for configuration options
@@ -14,8 +49,10 @@ type (
 
 func defaultConfig() *Config {
 	return &Config{
-		Name:            defaultAppName,
-		CredentialsPath: "firebase-credentials.json",
+		Name:               defaultAppName,
+		IdentityToolkitURL: identityToolkitURL,
+		SecureTokenURL:     secureTokenURL,
+		AuthEmulatorHost:   authEmulatorHost(),
 	}
 }
 
@@ -27,7 +64,8 @@ func WithName(name string) func(*Config) error {
 	}
 }
 
-// WithCredentialsPath sets the path to load credentials from
+// WithCredentialsPath sets the path to load credentials from, instead of
+// looking for Application Default Credentials
 func WithCredentialsPath(path string) func(*Config) error {
 	return func(c *Config) error {
 		c.CredentialsPath = path
@@ -42,3 +80,116 @@ func WithCredentials(creds *Credentials) func(*Config) error {
 		return nil
 	}
 }
+
+// WithMetadataCredentials reads the project and service account from the
+// Compute Engine metadata server, instead of a key file. Access tokens come
+// from the metadata server and custom tokens are signed with the IAM
+// Credentials API. GCE_METADATA_HOST overrides the metadata server host
+func WithMetadataCredentials() func(*Config) error {
+	return func(c *Config) error {
+		c.MetadataCredentials = true
+		return nil
+	}
+}
+
+// WithProjectID sets the project ID, overriding the credentials and the
+// FIREBASE_CONFIG and GOOGLE_CLOUD_PROJECT environment variables
+func WithProjectID(projectID string) func(*Config) error {
+	return func(c *Config) error {
+		c.ProjectID = projectID
+		return nil
+	}
+}
+
+// WithIdentityToolkitURL sets the base URL for Identity Toolkit REST calls
+func WithIdentityToolkitURL(url string) func(*Config) error {
+	return func(c *Config) error {
+		c.IdentityToolkitURL = strings.TrimSuffix(url, "/")
+		return nil
+	}
+}
+
+// WithSecureTokenURL sets the base URL for exchanging refresh tokens for
+// ID tokens
+func WithSecureTokenURL(url string) func(*Config) error {
+	return func(c *Config) error {
+		c.SecureTokenURL = strings.TrimSuffix(url, "/")
+		return nil
+	}
+}
+
+// WithAPIKey sets the Web API key used for Identity Toolkit REST calls
+func WithAPIKey(key string) func(*Config) error {
+	return func(c *Config) error {
+		c.APIKey = key
+		return nil
+	}
+}
+
+// WithAuthEmulator connects the app to the Firebase Auth Emulator at host,
+// overriding the FIREBASE_AUTH_EMULATOR_HOST environment variable. Admin
+// requests are then authorized with the emulator's "owner" token, unless a
+// source is set with WithAccessTokenSource.
+func WithAuthEmulator(host string) func(*Config) error {
+	return func(c *Config) error {
+		c.AuthEmulatorHost = host
+		return nil
+	}
+}
+
+// WithKeySource sets the source of public keys used to verify ID tokens
+func WithKeySource(keys KeySource) func(*Config) error {
+	return func(c *Config) error {
+		c.KeySource = keys
+		return nil
+	}
+}
+
+// WithSessionKeySource sets the source of public keys used to verify
+// session cookies
+func WithSessionKeySource(keys KeySource) func(*Config) error {
+	return func(c *Config) error {
+		c.SessionKeySource = keys
+		return nil
+	}
+}
+
+// WithSigner sets the signer used to create custom tokens, instead of the
+// credentials private key
+func WithSigner(signer Signer) func(*Config) error {
+	return func(c *Config) error {
+		c.Signer = signer
+		return nil
+	}
+}
+
+// WithAccessTokenSource sets the source of OAuth2 access tokens used to
+// authorize admin REST calls, instead of the credentials private key
+func WithAccessTokenSource(tokens AccessTokenSource) func(*Config) error {
+	return func(c *Config) error {
+		c.AccessTokenSource = tokens
+		return nil
+	}
+}
+
+// WithHTTPClient sets the client the app makes outbound requests with. It's
+// the last choice before http.DefaultClient: a client set as the HTTPClient
+// context value, or else one provided by a registered ContextClientFunc such
+// as the App Engine urlfetch client, is used in its place. The client is
+// used as is, without the default timeout or the library User-Agent; use
+// WithTransport to keep those
+func WithHTTPClient(client *http.Client) func(*Config) error {
+	return func(c *Config) error {
+		c.HTTPClient = client
+		return nil
+	}
+}
+
+// WithTransport sets the transport the app makes outbound requests with,
+// using the default timeout and the library User-Agent
+func WithTransport(transport http.RoundTripper) func(*Config) error {
+	return func(c *Config) error {
+		c.HTTPClient = newHTTPClient(transport)
+		return nil
+	}
+}
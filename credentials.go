@@ -2,46 +2,177 @@ package firebase
 
 import (
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"io"
+)
+
+const (
+	// Type of a service account key file
+	serviceAccountType = "service_account"
+
+	// Type of the user credentials written by gcloud
+	userCredentialsType = "authorized_user"
 
-	"github.com/SermoDigital/jose/crypto"
+	// Default endpoint to exchange a service account assertion for a token
+	defaultTokenURI = "https://oauth2.googleapis.com/token"
 )
 
 type (
 	Credentials struct {
+		// Type is the credentials type, which is always "service_account".
+		Type string
 		// ProjectID is the project ID.
 		ProjectID string
+		// PrivateKeyID is the ID of the private key.
+		PrivateKeyID string
 		// PrivateKey is the RSA256 private key.
 		PrivateKey *rsa.PrivateKey
 		// ClientEmail is the client email.
 		ClientEmail string
+		// ClientID is the unique ID of the service account.
+		ClientID string
+		// AuthURI is the OAuth2 authorization endpoint.
+		AuthURI string
+		// TokenURI is the OAuth2 token endpoint.
+		TokenURI string
+		// AuthProviderX509CertURL is the URL of the Google OAuth2 certificates.
+		AuthProviderX509CertURL string
+		// ClientX509CertURL is the URL of the service account certificates.
+		ClientX509CertURL string
+		// UniverseDomain is the Google Cloud universe the account belongs to.
+		UniverseDomain string
+	}
+
+	// credentialsJSON is the service account key file format.
+	credentialsJSON struct {
+		Type                    string `json:"type"`
+		ProjectID               string `json:"project_id,omitempty"`
+		PrivateKeyID            string `json:"private_key_id,omitempty"`
+		PrivateKey              string `json:"private_key"`
+		ClientEmail             string `json:"client_email"`
+		ClientID                string `json:"client_id,omitempty"`
+		AuthURI                 string `json:"auth_uri,omitempty"`
+		TokenURI                string `json:"token_uri,omitempty"`
+		AuthProviderX509CertURL string `json:"auth_provider_x509_cert_url,omitempty"`
+		ClientX509CertURL       string `json:"client_x509_cert_url,omitempty"`
+		UniverseDomain          string `json:"universe_domain,omitempty"`
 	}
 )
 
 // UnmarshalJSON is the custom unmarshaler for GoogleServiceAccountCredential.
-// Private key is parsed from PEM format.
+// Private key is parsed from PEM format, in either PKCS#1 or PKCS#8 form.
+// Other kinds of credentials, such as user credentials from gcloud, are
+// rejected with an error saying what they are.
 func (c *Credentials) UnmarshalJSON(data []byte) error {
-	var aux struct {
-		ProjectID   string `json:"project_id"`
-		PrivateKey  string `json:"private_key"`
-		ClientEmail string `json:"client_email"`
-	}
+	var aux credentialsJSON
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
-	privKey, err := crypto.ParseRSAPrivateKeyFromPEM([]byte(aux.PrivateKey))
+	switch aux.Type {
+	case serviceAccountType, "":
+	case userCredentialsType:
+		return errors.New("credentials are user credentials from gcloud, a service account key is required")
+	case "external_account":
+		return errors.New("credentials are for workload identity federation, a service account key is required")
+	default:
+		return fmt.Errorf("credentials type %q is not supported, a service account key is required", aux.Type)
+	}
+
+	if aux.ClientEmail == "" {
+		return errors.New("service account credentials have no client_email")
+	}
+	if aux.PrivateKey == "" {
+		return errors.New("service account credentials have no private_key")
+	}
+
+	privKey, err := parsePrivateKey([]byte(aux.PrivateKey))
 	if err != nil {
 		return err
 	}
-	c.PrivateKey = privKey
 
-	c.ProjectID = aux.ProjectID
-	c.ClientEmail = aux.ClientEmail
+	if aux.Type == "" {
+		aux.Type = serviceAccountType
+	}
+	if aux.TokenURI == "" {
+		aux.TokenURI = defaultTokenURI
+	}
+
+	*c = Credentials{
+		Type:                    aux.Type,
+		ProjectID:               aux.ProjectID,
+		PrivateKeyID:            aux.PrivateKeyID,
+		PrivateKey:              privKey,
+		ClientEmail:             aux.ClientEmail,
+		ClientID:                aux.ClientID,
+		AuthURI:                 aux.AuthURI,
+		TokenURI:                aux.TokenURI,
+		AuthProviderX509CertURL: aux.AuthProviderX509CertURL,
+		ClientX509CertURL:       aux.ClientX509CertURL,
+		UniverseDomain:          aux.UniverseDomain,
+	}
 	return nil
 }
 
+// MarshalJSON writes the credentials in the service account key file
+// format, with the private key in PKCS#8 PEM form. Credentials without a
+// private key, such as those from the metadata server, can't be written as
+// a key file.
+func (c Credentials) MarshalJSON() ([]byte, error) {
+	if c.PrivateKey == nil {
+		return nil, errors.New("credentials have no private key to write as a service account key file")
+	}
+
+	aux := credentialsJSON{
+		Type:                    c.Type,
+		ProjectID:               c.ProjectID,
+		PrivateKeyID:            c.PrivateKeyID,
+		ClientEmail:             c.ClientEmail,
+		ClientID:                c.ClientID,
+		AuthURI:                 c.AuthURI,
+		TokenURI:                c.TokenURI,
+		AuthProviderX509CertURL: c.AuthProviderX509CertURL,
+		ClientX509CertURL:       c.ClientX509CertURL,
+		UniverseDomain:          c.UniverseDomain,
+	}
+	if aux.Type == "" {
+		aux.Type = serviceAccountType
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	aux.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	return json.Marshal(aux)
+}
+
+// parsePrivateKey parses a PEM encoded RSA private key in PKCS#1
+// ("RSA PRIVATE KEY") or PKCS#8 ("PRIVATE KEY") form.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private_key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private_key is not a PKCS#1 or PKCS#8 key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private_key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
 // loadCredential loads the Service Account credential from a JSON file.
 func loadCredential(r io.Reader) (*Credentials, error) {
 	var c Credentials
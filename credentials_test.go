@@ -1,8 +1,18 @@
 package firebase
 
 import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+
+	"golang.org/x/net/context"
 )
 
 func TestCredentials(t *testing.T) {
@@ -19,3 +29,86 @@ func TestCredentials(t *testing.T) {
 	// t.Logf("private key %v", c.PrivateKey)
 	// t.Logf("project id %s", c.ProjectID)
 }
+
+func TestCredentialsJSON(t *testing.T) {
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(testKey)
+	keys := map[string]string{
+		"pkcs1": string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testKey)})),
+		"pkcs8": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+	}
+
+	for name, key := range keys {
+		data, _ := json.Marshal(map[string]string{
+			"type":           "service_account",
+			"project_id":     testProjectID,
+			"private_key_id": testKeyID,
+			"private_key":    key,
+			"client_email":   testClientEmail,
+			"client_id":      "1234",
+			"token_uri":      "https://oauth2.googleapis.com/token",
+		})
+
+		var c Credentials
+		if err := json.Unmarshal(data, &c); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if c.ClientID != "1234" || c.PrivateKeyID != testKeyID || c.PrivateKey.N.Cmp(testKey.N) != 0 {
+			t.Errorf("%s: unexpected credentials %+v", name, c)
+		}
+
+		// the credentials round-trip, whether marshaled as a value or a
+		// pointer, and the key still signs
+		for _, v := range []interface{}{c, &c} {
+			b, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var c2 Credentials
+			if err := json.Unmarshal(b, &c2); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c, c2) {
+				t.Errorf("%s: expected %+v, got %+v", name, c, c2)
+			}
+
+			data := []byte("data")
+			sig, err := NewRSASigner(c2.ClientEmail, c2.PrivateKey).Sign(context.Background(), data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hash := sha256.Sum256(data)
+			if err := rsa.VerifyPKCS1v15(&testKey.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	// credentials without a key wouldn't read back as a key file, so they
+	// aren't written as one
+	keyless := Credentials{ProjectID: testProjectID, ClientEmail: testClientEmail}
+	if b, err := json.Marshal(keyless); err == nil {
+		var c Credentials
+		if err := json.Unmarshal(b, &c); err != nil || !reflect.DeepEqual(c, keyless) {
+			t.Errorf("expected keyless credentials to round-trip or fail to marshal, got %s", b)
+		}
+	}
+
+	invalid := map[string]string{
+		"user":     `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`,
+		"external": `{"type":"external_account","audience":"aud"}`,
+		"no key":   `{"type":"service_account","client_email":"sa@example.com"}`,
+		"bad key":  `{"type":"service_account","client_email":"sa@example.com","private_key":"not a key"}`,
+	}
+	for name, data := range invalid {
+		var c Credentials
+		err := json.Unmarshal([]byte(data), &c)
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+			continue
+		}
+		if name == "user" && !strings.Contains(err.Error(), "user credentials") {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"github.com/SermoDigital/jose/jwt"
+	"golang.org/x/net/context"
+)
+
+const (
+	// URL prefix of the public certificates for a service account
+	serviceAccountCertURLPrefix = "https://www.googleapis.com/robot/v1/metadata/x509/"
+)
+
+type (
+	// CustomToken is a custom token verified by VerifyCustomToken.
+	CustomToken struct {
+		jwt.JWT
+	}
+)
+
+// UID returns the uid the custom token signs in.
+func (t *CustomToken) UID() (string, bool) {
+	uid, ok := t.Claims().Get("uid").(string)
+	return uid, ok
+}
+
+// DeveloperClaims returns the developer claims added to the custom token.
+func (t *CustomToken) DeveloperClaims() (Claims, bool) {
+	claims, ok := t.Claims().Get("claims").(map[string]interface{})
+	return Claims(claims), ok
+}
+
+// TenantID returns the tenant the custom token signs in to.
+func (t *CustomToken) TenantID() (string, bool) {
+	tenant, ok := t.Claims().Get("tenant_id").(string)
+	return tenant, ok
+}
+
+// VerifyCustomToken verifies a custom token created by CreateCustomToken for
+// this app's service account. The signature is checked against the public
+// key of the credentials, or else against the service account's published
+// certificates.
+func (a *Auth) VerifyCustomToken(ctx context.Context, token string) (*CustomToken, error) {
+	ctx, err := a.app.context(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedJWT, err := jws.ParseJWT([]byte(token))
+	if err != nil {
+		return nil, malformedError(err)
+	}
+
+	decodedJWS, ok := decodedJWT.(jws.JWS)
+	if !ok {
+		return nil, fmt.Errorf("%w: custom token cannot be decoded", ErrMalformedToken)
+	}
+
+	// the emulator mints unsigned tokens
+	if a.app.IsEmulator() {
+		if err := decodedJWT.Validate(nil, crypto.Unsecured, a.customTokenValidator()); err != nil {
+			return nil, verifyError(err)
+		}
+		return &CustomToken{decodedJWT}, nil
+	}
+
+	email := a.serviceAccountEmail()
+	if email == "" {
+		return nil, errors.New("Must provide a signer or credentials with a client email.")
+	}
+
+	keys, err := a.serviceAccountKeys(ctx, email, decodedJWS)
+	if err != nil {
+		return nil, verifyError(err)
+	}
+
+	// tokens without a key ID could be signed by any of the account's keys
+	for _, key := range keys {
+		if err = decodedJWS.Verify(key, crypto.SigningMethodRS256); err == nil {
+			if err := decodedJWT.Validate(key, crypto.SigningMethodRS256, a.customTokenValidator()); err != nil {
+				return nil, verifyError(err)
+			}
+			return &CustomToken{decodedJWT}, nil
+		}
+	}
+	if err == nil {
+		err = &KeyNotFoundError{}
+	}
+	return nil, verifyError(err)
+}
+
+// serviceAccountEmail returns the service account custom tokens are issued
+// by, which is the signer if there is one.
+func (a *Auth) serviceAccountEmail() string {
+	if a.app.signer != nil {
+		return a.app.signer.Email()
+	}
+	return a.app.creds.ClientEmail
+}
+
+// serviceAccountKeys returns the public keys that may have signed the token.
+// The signer's key ring, or else the credentials private key, is used when
+// it belongs to the account.
+func (a *Auth) serviceAccountKeys(ctx context.Context, email string, j jws.JWS) ([]interface{}, error) {
+	if ring, ok := a.app.signer.(*KeyRing); ok && ring.Email() == email {
+		if kid, ok := j.Protected().Get("kid").(string); ok {
+			key, err := ring.PublicKey(ctx, kid)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{key}, nil
+		}
+		return ring.publicKeys(), nil
+	}
+
+	creds := a.app.creds
+	if creds.PrivateKey != nil && creds.ClientEmail == email {
+		return []interface{}{&creds.PrivateKey.PublicKey}, nil
+	}
+
+	store, err := a.app.serviceAccountCerts(email)
+	if err != nil {
+		return nil, err
+	}
+	if kid, ok := j.Protected().Get("kid").(string); ok {
+		key, err := store.PublicKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{key}, nil
+	}
+	return store.publicKeys(ctx)
+}
+
+// serviceAccountCerts returns the certificate store for the service account,
+// creating it the first time it's used. It returns ErrAppDeleted once the
+// app has been deleted, so no store outlives it.
+func (a *App) serviceAccountCerts(email string) (*certificateStore, error) {
+	a.accountCerts.Lock()
+	defer a.accountCerts.Unlock()
+
+	if atomic.LoadInt32(&a.deleted) != 0 {
+		return nil, ErrAppDeleted
+	}
+	if a.accountCerts.m == nil {
+		a.accountCerts.m = make(map[string]*certificateStore)
+	}
+	store, ok := a.accountCerts.m[email]
+	if !ok {
+		store = newCertificateStore(serviceAccountCertURLPrefix + email)
+		a.accountCerts.m[email] = store
+	}
+	return store, nil
+}
+
+// customTokenValidator checks the custom token was issued by the service
+// account for Firebase Auth and, for a tenant-scoped Auth, the tenant.
+func (a *Auth) customTokenValidator() *jwt.Validator {
+	email := a.serviceAccountEmail()
+	if email == "" && a.app.IsEmulator() {
+		email = emulatorServiceAccount
+	}
+
+	v := &jwt.Validator{}
+	v.EXP = a.verify.clockSkew
+	v.NBF = a.verify.clockSkew
+	v.SetIssuer(email)
+	v.SetSubject(email)
+	v.Fn = func(claims jwt.Claims) error {
+		aud, _ := claims.Audience()
+		if len(aud) != 1 || aud[0] != firebaseAudience {
+			return jwt.ErrInvalidAUDClaim
+		}
+		if iat, ok := claims.IssuedAt(); !ok || iat.After(clock.Now().Add(a.verify.clockSkew)) {
+			return jwt.ErrTokenNotYetValid
+		}
+		if uid, ok := claims.Get("uid").(string); !ok || len(uid) == 0 || len(uid) > 128 {
+			return jwt.ErrInvalidSUBClaim
+		}
+		if a.tenantID != "" {
+			if tenant, _ := claims.Get("tenant_id").(string); tenant != a.tenantID {
+				return ErrInvalidTenant
+			}
+		}
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package firebase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestVerifyCustomToken(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	ctx := context.Background()
+	local := &App{creds: &Credentials{ProjectID: testProjectID, ClientEmail: testClientEmail, PrivateKey: testKey}}
+	local.signer = &rsaSigner{email: testClientEmail, keyID: testKeyID, key: testKey}
+
+	// without the private key the account's certificates are downloaded
+	remote := &App{creds: &Credentials{ProjectID: testProjectID}}
+	remote.signer = &rsaSigner{email: testClientEmail, keyID: testKeyID, key: testKey}
+	remote.serviceAccountCerts(testClientEmail)
+	remote.accountCerts.m[testClientEmail] = newCertificateStore(certSrv.URL)
+
+	for name, app := range map[string]*App{"local": local, "remote": remote} {
+		for _, includeKeyID := range []bool{false, true} {
+			s, err := app.TenantAuth("tenant-1").CreateCustomTokenWithOptions(ctx, "uid1", &CustomTokenOptions{
+				Claims:       &Claims{"role": "admin"},
+				IncludeKeyID: includeKeyID,
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+
+			token, err := app.TenantAuth("tenant-1").VerifyCustomToken(ctx, s)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			if uid, _ := token.UID(); uid != "uid1" {
+				t.Errorf("%s: expected uid1, got %s", name, uid)
+			}
+			if claims, _ := token.DeveloperClaims(); claims["role"] != "admin" {
+				t.Errorf("%s: expected role claim, got %v", name, claims)
+			}
+
+			if _, err := app.TenantAuth("tenant-2").VerifyCustomToken(ctx, s); err != ErrInvalidTenant {
+				t.Errorf("%s: expected ErrInvalidTenant, got %v", name, err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"malformed", "a.b", ErrMalformedToken},
+		{"id token", testIDToken(t, "uid1", time.Now()), ErrInvalidIssuer},
+	}
+	for _, test := range tests {
+		if _, err := local.Auth().VerifyCustomToken(ctx, test.token); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	other := &App{creds: &Credentials{ProjectID: testProjectID}, signer: NewRSASigner("other@example.com", testKey)}
+	s, _ := other.Auth().CreateCustomToken(ctx, "uid1", nil)
+	if _, err := local.Auth().VerifyCustomToken(ctx, s); !errors.Is(err, ErrInvalidIssuer) {
+		t.Errorf("expected ErrInvalidIssuer, got %v", err)
+	}
+}
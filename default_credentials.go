@@ -0,0 +1,167 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Environment variable holding the path of the credentials file
+	credentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+
+	// Environment variable overriding the project ID
+	projectEnv = "GOOGLE_CLOUD_PROJECT"
+
+	// Environment variable holding the Firebase config JSON, or its path
+	firebaseConfigEnv = "FIREBASE_CONFIG"
+
+	// Environment variable overriding the gcloud config directory
+	gcloudConfigEnv = "CLOUDSDK_CONFIG"
+
+	// Credentials file the working directory used to be required to have,
+	// deprecated in favor of GOOGLE_APPLICATION_CREDENTIALS
+	legacyCredentialsPath = "firebase-credentials.json"
+
+	// Name of the credentials file written by gcloud auth application-default login
+	wellKnownCredentialsFile = "application_default_credentials.json"
+)
+
+// platformCredentials returns the credentials of the platform the app runs
+// on, if it has any without a metadata server. It's set by the classic App
+// Engine hook.
+var platformCredentials func() *Credentials
+
+// findDefaultCredentials looks for credentials in the Application Default
+// Credentials order: the file named by GOOGLE_APPLICATION_CREDENTIALS, the
+// gcloud well-known file and then the metadata server. The deprecated
+// firebase-credentials.json in the working directory, and then the platform
+// credentials, are still tried before the metadata server. It reports whether the credentials came from the
+// metadata server.
+func findDefaultCredentials(ctx context.Context) (*Credentials, bool, error) {
+	if path := os.Getenv(credentialsEnv); path != "" {
+		c, err := loadCredentialFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", credentialsEnv, err)
+		}
+		return c, false, nil
+	}
+
+	if path := wellKnownCredentialsPath(); path != "" {
+		if _, err := os.Stat(path); err == nil {
+			c, err := loadWellKnownCredentials(path)
+			return c, false, err
+		}
+	}
+
+	if _, err := os.Stat(legacyCredentialsPath); err == nil {
+		c, err := loadCredentialFile(legacyCredentialsPath)
+		return c, false, err
+	}
+
+	if platformCredentials != nil {
+		if c := platformCredentials(); c != nil {
+			return c, false, nil
+		}
+	}
+
+	c, err := metadataCredentials(ctx)
+	if err != nil {
+		return nil, false, errors.New("Could not find default credentials. Set " + credentialsEnv + " to the path of a service account key file.")
+	}
+	return c, true, nil
+}
+
+// wellKnownCredentialsPath returns the path of the credentials file gcloud
+// writes for Application Default Credentials.
+func wellKnownCredentialsPath() string {
+	if dir := os.Getenv(gcloudConfigEnv); dir != "" {
+		return filepath.Join(dir, wellKnownCredentialsFile)
+	}
+	if runtime.GOOS == "windows" {
+		if dir := os.Getenv("APPDATA"); dir != "" {
+			return filepath.Join(dir, "gcloud", wellKnownCredentialsFile)
+		}
+		return ""
+	}
+	if dir := os.Getenv("HOME"); dir != "" {
+		return filepath.Join(dir, ".config", "gcloud", wellKnownCredentialsFile)
+	}
+	return ""
+}
+
+// loadCredentialFile loads service account credentials from a JSON key
+// file.
+func loadCredentialFile(path string) (*Credentials, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// loadWellKnownCredentials loads the credentials gcloud writes for
+// Application Default Credentials. These are usually user credentials,
+// which only provide the quota project: enough to verify tokens but not to
+// sign them.
+func loadWellKnownCredentials(path string) (*Credentials, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var aux struct {
+		Type           string `json:"type"`
+		QuotaProjectID string `json:"quota_project_id"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return nil, err
+	}
+	if aux.Type == userCredentialsType {
+		return &Credentials{Type: userCredentialsType, ProjectID: aux.QuotaProjectID}, nil
+	}
+
+	var c Credentials
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// projectIDOverride returns the project ID set by the FIREBASE_CONFIG or
+// GOOGLE_CLOUD_PROJECT environment variables, in that order. It's only used
+// when the credentials don't name the project for the app. FIREBASE_CONFIG
+// holds either the config JSON or the path of a file containing it.
+func projectIDOverride() (string, error) {
+	if config := os.Getenv(firebaseConfigEnv); config != "" {
+		b := []byte(config)
+		if !strings.HasPrefix(strings.TrimSpace(config), "{") {
+			var err error
+			if b, err = ioutil.ReadFile(config); err != nil {
+				return "", fmt.Errorf("%s: %v", firebaseConfigEnv, err)
+			}
+		}
+		var aux struct {
+			ProjectID string `json:"projectId"`
+		}
+		if err := json.Unmarshal(b, &aux); err != nil {
+			return "", fmt.Errorf("%s: %v", firebaseConfigEnv, err)
+		}
+		if aux.ProjectID != "" {
+			return aux.ProjectID, nil
+		}
+	}
+	return os.Getenv(projectEnv), nil
+}
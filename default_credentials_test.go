@@ -0,0 +1,164 @@
+package firebase
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// setenv sets environment variables for the test, returning a func to
+// restore them.
+func setenv(vars map[string]string) func() {
+	saved := make(map[string]*string)
+	for k, v := range vars {
+		if old, ok := os.LookupEnv(k); ok {
+			saved[k] = &old
+		} else {
+			saved[k] = nil
+		}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, v := range saved {
+			if v == nil {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestFindDefaultCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serviceAccount, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     testProjectID,
+		"private_key_id": testKeyID,
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testKey)})),
+		"client_email":   testClientEmail,
+	})
+	keyPath := filepath.Join(dir, "key.json")
+	ioutil.WriteFile(keyPath, serviceAccount, 0600)
+
+	os.MkdirAll(filepath.Join(dir, "gcloud"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "gcloud", wellKnownCredentialsFile), []byte(`{"type":"authorized_user","quota_project_id":"user-project"}`), 0600)
+
+	metadataSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			t.Errorf("missing metadata header")
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("metadata-project"))
+		case "/computeMetadata/v1/instance/service-accounts/default/email":
+			w.Write([]byte("default@metadata-project.iam.gserviceaccount.com"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metadataSrv.Close()
+
+	defer setenv(map[string]string{
+		credentialsEnv:  keyPath,
+		gcloudConfigEnv: filepath.Join(dir, "gcloud"),
+		metadataHostEnv: strings.TrimPrefix(metadataSrv.URL, "http://"),
+	})()
+	ctx := context.Background()
+
+	c, _, err := findDefaultCredentials(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ProjectID != testProjectID || c.PrivateKey == nil || c.PrivateKeyID != testKeyID {
+		t.Errorf("expected service account credentials, got %+v", c)
+	}
+
+	os.Unsetenv(credentialsEnv)
+	if c, _, err = findDefaultCredentials(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.ProjectID != "user-project" || c.PrivateKey != nil {
+		t.Errorf("expected user credentials, got %+v", c)
+	}
+
+	// user credentials are only accepted from the gcloud well-known file
+	userPath := filepath.Join(dir, "gcloud", wellKnownCredentialsFile)
+	if _, err := NewApp(WithCredentialsPath(userPath)); err == nil || !strings.Contains(err.Error(), "user credentials") {
+		t.Errorf("expected user credentials error, got %v", err)
+	}
+
+	os.Setenv(gcloudConfigEnv, filepath.Join(dir, "missing"))
+	c, fromMetadata, err := findDefaultCredentials(ctx)
+	if err != nil || !fromMetadata {
+		t.Fatal(err)
+	}
+	if c.ProjectID != "metadata-project" || c.ClientEmail != "default@metadata-project.iam.gserviceaccount.com" {
+		t.Errorf("expected metadata credentials, got %+v", c)
+	}
+}
+
+func TestProjectIDOverride(t *testing.T) {
+	defer setenv(map[string]string{
+		firebaseConfigEnv: `{"projectId":"firebase-project"}`,
+		projectEnv:        "cloud-project",
+	})()
+
+	// the project of explicit credentials is kept
+	app, err := NewApp(WithCredentials(&Credentials{ProjectID: testProjectID}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != testProjectID {
+		t.Errorf("expected credentials project, got %s", app.creds.ProjectID)
+	}
+
+	// but credentials without a project, or gcloud user credentials, take
+	// it from the environment
+	for _, c := range []*Credentials{{}, {Type: userCredentialsType, ProjectID: "user-project"}} {
+		app, err := NewApp(WithCredentials(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer app.Delete()
+		if app.creds.ProjectID != "firebase-project" {
+			t.Errorf("expected FIREBASE_CONFIG project, got %s", app.creds.ProjectID)
+		}
+	}
+
+	// WithProjectID always wins
+	app, err = NewApp(WithCredentials(&Credentials{ProjectID: testProjectID}), WithProjectID("explicit-project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != "explicit-project" {
+		t.Errorf("expected explicit project, got %s", app.creds.ProjectID)
+	}
+
+	os.Unsetenv(firebaseConfigEnv)
+	if projectID, _ := projectIDOverride(); projectID != "cloud-project" {
+		t.Errorf("expected GOOGLE_CLOUD_PROJECT project, got %s", projectID)
+	}
+
+	os.Unsetenv(projectEnv)
+	if _, err := NewApp(WithCredentials(&Credentials{})); err == nil {
+		t.Error("expected error without a project ID")
+	}
+}
@@ -0,0 +1,64 @@
+package firebase
+
+import (
+	"os"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Environment variable holding the host:port of the Auth Emulator
+	authEmulatorHostEnv = "FIREBASE_AUTH_EMULATOR_HOST"
+
+	// Environment variable holding the project ID, as set by the emulators
+	gcloudProjectEnv = "GCLOUD_PROJECT"
+
+	// Issuer used for custom tokens when no service account is available
+	emulatorServiceAccount = "firebase-auth-emulator@example.com"
+
+	// Access token the Auth Emulator accepts for admin requests
+	emulatorAccessToken = "owner"
+)
+
+type (
+	// emulatorTokenSource provides the emulator's admin token, so no token
+	// is ever exchanged with Google while using the emulator.
+	emulatorTokenSource struct{}
+)
+
+// authEmulatorHost returns the Auth Emulator host set in the environment.
+func authEmulatorHost() string {
+	return os.Getenv(authEmulatorHostEnv)
+}
+
+// emulatorProjectID returns the project ID set in the environment for apps
+// using the Auth Emulator, which don't need credentials.
+func emulatorProjectID() string {
+	if projectID := os.Getenv(gcloudProjectEnv); projectID != "" {
+		return projectID
+	}
+	return os.Getenv(projectEnv)
+}
+
+// emulatorIdentityToolkitURL returns the Identity Toolkit base URL served by
+// the Auth Emulator running on host.
+func emulatorIdentityToolkitURL(host string) string {
+	return "http://" + host + "/identitytoolkit.googleapis.com/v1"
+}
+
+// emulatorSecureTokenURL returns the Secure Token base URL served by the
+// Auth Emulator running on host.
+func emulatorSecureTokenURL(host string) string {
+	return "http://" + host + "/securetoken.googleapis.com/v1"
+}
+
+// IsEmulator reports whether the app talks to the Firebase Auth Emulator.
+// Tokens are then created and verified unsigned.
+func (a *App) IsEmulator() bool {
+	return a.emulatorHost != ""
+}
+
+// AccessToken implements AccessTokenSource.
+func (emulatorTokenSource) AccessToken(ctx context.Context) (*AccessToken, error) {
+	return &AccessToken{Token: emulatorAccessToken}, nil
+}
@@ -0,0 +1,138 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+func TestAuthEmulator(t *testing.T) {
+	app, err := New(
+		WithName("emulator-test"),
+		WithCredentials(&Credentials{ProjectID: testProjectID}),
+		WithAuthEmulator("localhost:9099"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.identityToolkitURL != "http://localhost:9099/identitytoolkit.googleapis.com/v1" {
+		t.Errorf("unexpected identity toolkit URL %s", app.identityToolkitURL)
+	}
+	if app.secureTokenURL != "http://localhost:9099/securetoken.googleapis.com/v1" {
+		t.Errorf("unexpected secure token URL %s", app.secureTokenURL)
+	}
+
+	auth := app.Auth()
+	custom, err := auth.CreateCustomToken(context.Background(), "uid1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(custom, ".") {
+		t.Errorf("expected unsigned custom token, got %s", custom)
+	}
+
+	now := time.Now()
+	claims := jws.Claims{}
+	claims.SetIssuer(idTokenIssuerPrefix + testProjectID)
+	claims.SetAudience(testProjectID)
+	claims.SetSubject("uid1")
+	claims.SetIssuedAt(now)
+	claims.SetExpiration(now.Add(time.Hour))
+	b, err := jws.NewJWT(claims, crypto.Unsecured).Serialize(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if _, err := auth.VerifyIDToken(ctx, string(b)); err != nil {
+		t.Error(err)
+	}
+
+	// outside the emulator unsigned tokens must be rejected
+	prod := &App{creds: &Credentials{ProjectID: testProjectID}}
+	if _, err := prod.Auth().VerifyIDToken(ctx, string(b)); err == nil {
+		t.Error("expected unsigned token to be rejected")
+	}
+}
+
+func TestAuthEmulatorWithoutCredentials(t *testing.T) {
+	defer setenv(map[string]string{
+		credentialsEnv:   "missing.json",
+		gcloudProjectEnv: "emulator-project",
+	})()
+
+	app, err := NewApp(WithAuthEmulator("localhost:9099"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != "emulator-project" || app.creds.PrivateKey != nil {
+		t.Errorf("expected emulator project without a key, got %+v", app.creds)
+	}
+
+	if app, err = NewApp(WithAuthEmulator("localhost:9099"), WithProjectID(testProjectID)); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != testProjectID {
+		t.Errorf("expected project %s, got %s", testProjectID, app.creds.ProjectID)
+	}
+	if _, err := app.Auth().CreateCustomToken(context.Background(), "uid1", nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAuthEmulatorAccessToken(t *testing.T) {
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected access token exchange")
+		http.Error(w, "unexpected", http.StatusInternalServerError)
+	}))
+	defer tokenSrv.Close()
+
+	emulator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer owner" {
+			t.Errorf("expected owner token, got %q", auth)
+		}
+		w.Write([]byte(`{"sessionCookie":"cookie"}`))
+	}))
+	defer emulator.Close()
+
+	app, err := NewApp(
+		WithCredentials(&Credentials{
+			ProjectID:   testProjectID,
+			ClientEmail: testClientEmail,
+			PrivateKey:  testKey,
+			TokenURI:    tokenSrv.URL,
+		}),
+		WithAuthEmulator(strings.TrimPrefix(emulator.URL, "http://")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+
+	cookie, err := app.Auth().CreateSessionCookie(context.Background(), "id-token", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cookie != "cookie" {
+		t.Errorf("unexpected session cookie %s", cookie)
+	}
+
+	// a source given explicitly is kept
+	tokens := NewMetadataTokenSource()
+	if app, err = NewApp(WithProjectID(testProjectID), WithAuthEmulator("localhost:9099"), WithAccessTokenSource(tokens)); err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.tokens != tokens {
+		t.Errorf("expected the given access token source, got %v", app.tokens)
+	}
+}
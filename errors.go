@@ -0,0 +1,233 @@
+package firebase
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SermoDigital/jose/jwt"
+)
+
+// Errors returned when verifying tokens. Use errors.Is to check for them as
+// they may be wrapped with more detail.
+var (
+	// ErrMalformedToken is returned when the token can't be decoded.
+	ErrMalformedToken = errors.New("Firebase Auth token is malformed")
+
+	// ErrInvalidSignature is returned when the token signature doesn't verify.
+	ErrInvalidSignature = errors.New("Firebase Auth token has an invalid signature")
+
+	// ErrTokenExpired is returned when the token has expired.
+	ErrTokenExpired = errors.New("Firebase Auth token has expired")
+
+	// ErrTokenNotYetValid is returned when the token is used too early.
+	ErrTokenNotYetValid = errors.New("Firebase Auth token is not yet valid")
+
+	// ErrInvalidAudience is returned when the token is for another project.
+	ErrInvalidAudience = errors.New("Firebase Auth token has an invalid audience")
+
+	// ErrInvalidIssuer is returned when the token has the wrong issuer.
+	ErrInvalidIssuer = errors.New("Firebase Auth token has an invalid issuer")
+
+	// ErrInvalidSubject is returned when the token has a missing or invalid
+	// subject (uid).
+	ErrInvalidSubject = errors.New("Firebase Auth token has an invalid subject")
+
+	// ErrInvalidTenant is returned when a tenant-scoped Auth is given a token
+	// belonging to a different tenant, or to no tenant at all.
+	ErrInvalidTenant = errors.New("Firebase Auth token belongs to a different tenant")
+
+	// ErrAuthTooOld is returned when the user signed in longer ago than the
+	// maximum age allowed by VerifyMaxAuthAge.
+	ErrAuthTooOld = errors.New("Firebase Auth user signed in too long ago")
+
+	// ErrEmailNotVerified is returned by VerifyEmailVerified when the user's
+	// email address hasn't been verified.
+	ErrEmailNotVerified = errors.New("Firebase Auth email address is not verified")
+
+	// ErrSignInProviderNotAllowed is returned by VerifySignInProviders when
+	// the user signed in with another provider.
+	ErrSignInProviderNotAllowed = errors.New("Firebase Auth sign-in provider is not allowed")
+
+	// ErrInvalidClaims is returned when the VerifyClaims function rejects the
+	// token. The error is a *ClaimsValidationError.
+	ErrInvalidClaims = errors.New("Firebase Auth token claims are invalid")
+
+	// ErrIDTokenRevoked is returned when the ID token was issued before the
+	// user's tokens were revoked.
+	ErrIDTokenRevoked = errors.New("Firebase Auth ID Token has been revoked")
+
+	// ErrUserDisabled is returned when the user account has been disabled.
+	ErrUserDisabled = errors.New("Firebase Auth user account is disabled")
+
+	// ErrUserNotFound is returned when the user account no longer exists.
+	ErrUserNotFound = errors.New("Firebase Auth user not found")
+
+	// ErrEmailExists is returned when signing up with an email address that
+	// is already in use.
+	ErrEmailExists = errors.New("Firebase Auth email address already exists")
+
+	// ErrEmailNotFound is returned when signing in with an unknown email
+	// address.
+	ErrEmailNotFound = errors.New("Firebase Auth email address not found")
+
+	// ErrInvalidEmail is returned when the email address is badly formatted.
+	ErrInvalidEmail = errors.New("Firebase Auth email address is invalid")
+
+	// ErrInvalidPassword is returned when signing in with the wrong password.
+	ErrInvalidPassword = errors.New("Firebase Auth password is invalid")
+
+	// ErrInvalidCredentials is returned when signing in fails and the project
+	// doesn't say whether the email address or the password is wrong.
+	ErrInvalidCredentials = errors.New("Firebase Auth sign-in credentials are invalid")
+
+	// ErrWeakPassword is returned when signing up with a password that is
+	// too weak.
+	ErrWeakPassword = errors.New("Firebase Auth password is too weak")
+
+	// ErrInvalidCustomToken is returned when signing in with a custom token
+	// that Firebase rejects.
+	ErrInvalidCustomToken = errors.New("Firebase Auth custom token is invalid")
+
+	// ErrOperationNotAllowed is returned when the sign-in method is disabled
+	// for the project.
+	ErrOperationNotAllowed = errors.New("Firebase Auth sign-in method is not enabled")
+
+	// ErrTooManyAttempts is returned when requests are blocked because of
+	// unusual activity.
+	ErrTooManyAttempts = errors.New("Firebase Auth has blocked requests after too many attempts")
+
+	// ErrAppDeleted is returned when using an App after calling Delete.
+	ErrAppDeleted = errors.New("Firebase app has been deleted")
+
+	// ErrKeyNotFound is returned when no public key matches the token key ID.
+	// The error is a *KeyNotFoundError.
+	ErrKeyNotFound = errors.New("public key not found")
+
+	// ErrCertFetch is returned when the public keys can't be downloaded.
+	// The error is a *CertFetchError.
+	ErrCertFetch = errors.New("public keys could not be fetched")
+)
+
+type (
+	// KeyNotFoundError is returned when no public key matches the key ID
+	// in the token header.
+	KeyNotFoundError struct {
+		KeyID string
+	}
+
+	// ClaimsValidationError wraps the error returned by the VerifyClaims
+	// function.
+	ClaimsValidationError struct {
+		Err error
+	}
+
+	// CertFetchError is returned when downloading or parsing the public keys
+	// fails. StatusCode is set when the server returned a non-200 response.
+	CertFetchError struct {
+		URL        string
+		StatusCode int
+		Err        error
+	}
+)
+
+func (e *KeyNotFoundError) Error() string {
+	return fmt.Sprintf("certificate not found for key ID: %s", e.KeyID)
+}
+
+// Is reports whether target is ErrKeyNotFound.
+func (e *KeyNotFoundError) Is(target error) bool {
+	return target == ErrKeyNotFound
+}
+
+func (e *ClaimsValidationError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrInvalidClaims, e.Err)
+}
+
+// Is reports whether target is ErrInvalidClaims.
+func (e *ClaimsValidationError) Is(target error) bool {
+	return target == ErrInvalidClaims
+}
+
+func (e *ClaimsValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CertFetchError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("download %s fails: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("download %s fails: %v", e.URL, e.Err)
+}
+
+// Is reports whether target is ErrCertFetch.
+func (e *CertFetchError) Is(target error) bool {
+	return target == ErrCertFetch
+}
+
+func (e *CertFetchError) Unwrap() error {
+	return e.Err
+}
+
+// verifyError translates errors from the jose library into the package
+// errors above. Errors that are already ours are returned unchanged.
+func verifyError(err error) error {
+	switch err {
+	case jwt.ErrTokenIsExpired:
+		return ErrTokenExpired
+	case jwt.ErrTokenNotYetValid:
+		return ErrTokenNotYetValid
+	case jwt.ErrInvalidAUDClaim:
+		return ErrInvalidAudience
+	case jwt.ErrInvalidISSClaim:
+		return ErrInvalidIssuer
+	case jwt.ErrInvalidSUBClaim:
+		return ErrInvalidSubject
+	}
+
+	var keyErr *KeyNotFoundError
+	var fetchErr *CertFetchError
+	switch {
+	case errors.As(err, &keyErr), errors.As(err, &fetchErr):
+		return err
+	case errors.Is(err, ErrMalformedToken), errors.Is(err, ErrInvalidTenant):
+		return err
+	}
+
+	// anything else comes from checking the signature
+	return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+}
+
+// malformedError wraps an error from decoding a token as ErrMalformedToken.
+func malformedError(err error) error {
+	return fmt.Errorf("%w: %v", ErrMalformedToken, err)
+}
+
+// errorStatus returns the HTTP status code to respond with when verifying a
+// token fails with err.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrCertFetch):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrMalformedToken):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUserDisabled),
+		errors.Is(err, ErrEmailNotVerified),
+		errors.Is(err, ErrSignInProviderNotAllowed),
+		errors.Is(err, ErrInvalidClaims):
+		return http.StatusForbidden
+	case errors.Is(err, ErrInvalidSignature),
+		errors.Is(err, ErrTokenExpired),
+		errors.Is(err, ErrTokenNotYetValid),
+		errors.Is(err, ErrInvalidAudience),
+		errors.Is(err, ErrInvalidIssuer),
+		errors.Is(err, ErrInvalidSubject),
+		errors.Is(err, ErrInvalidTenant),
+		errors.Is(err, ErrAuthTooOld),
+		errors.Is(err, ErrIDTokenRevoked),
+		errors.Is(err, ErrUserNotFound),
+		errors.Is(err, ErrKeyNotFound):
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
@@ -1,15 +1,50 @@
 package firebase // import "github.com/captaincodeman/go-firebase"
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
 )
 
 type App struct {
 	name  string
 	creds *Credentials
+
+	identityToolkitURL string
+	secureTokenURL     string
+	apiKey             string
+	emulatorHost       string
+
+	keys        KeySource
+	sessionKeys KeySource
+	signer      Signer
+	tokens      AccessTokenSource
+	client      *http.Client
+
+	// accountCerts holds the certificates of service accounts that custom
+	// tokens are verified against
+	accountCerts struct {
+		sync.Mutex
+		m map[string]*certificateStore
+	}
+
+	// owned are the key sources created by the app, which are closed when
+	// it's deleted
+	owned []closer
+
+	// deleted is set to 1 by Delete
+	deleted int32
+}
+
+// closer is implemented by key sources that have background work to stop.
+type closer interface {
+	close()
 }
 
 const (
@@ -39,7 +74,31 @@ func GetApp(name string) (*App, error) {
 	return app, nil
 }
 
+// New creates an App and registers it under its name, so it can be found
+// with GetApp. The name must not already be in use.
 func New(options ...Option) (*App, error) {
+	app, err := NewApp(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	apps.Lock()
+	_, exists := apps.m[app.name]
+	if !exists {
+		apps.m[app.name] = app
+	}
+	apps.Unlock()
+
+	if exists {
+		app.Delete()
+		return nil, fmt.Errorf("App %s already exists!", app.name)
+	}
+	return app, nil
+}
+
+// NewApp creates an App without registering it, so any number of apps with
+// the same name can exist, e.g. in tests.
+func NewApp(options ...Option) (*App, error) {
 	cfg := defaultConfig()
 	for _, option := range options {
 		if err := option(cfg); err != nil {
@@ -47,38 +106,238 @@ func New(options ...Option) (*App, error) {
 		}
 	}
 
-	if cfg.Credentials == nil {
-		r, err := os.Open(cfg.CredentialsPath)
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = newHTTPClient(nil)
+	}
+	ctx := withAppClient(context.Background(), cfg.HTTPClient)
+
+	// credentials from the metadata server come with its tokens
+	metadata := cfg.MetadataCredentials
+
+	switch {
+	case cfg.MetadataCredentials:
+		c, err := metadataCredentials(ctx)
 		if err != nil {
 			return nil, err
 		}
-		c, err := loadCredential(r)
+		cfg.Credentials = c
+	case cfg.Credentials != nil:
+		// copied so the project ID can be overridden
+		c := *cfg.Credentials
+		cfg.Credentials = &c
+	case cfg.CredentialsPath != "":
+		c, err := loadCredentialFile(cfg.CredentialsPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Credentials = c
+	case cfg.AuthEmulatorHost != "":
+		// the emulator doesn't check credentials, only the project is needed
+		cfg.Credentials = &Credentials{ProjectID: emulatorProjectID()}
+	default:
+		c, fromMetadata, err := findDefaultCredentials(ctx)
 		if err != nil {
 			return nil, err
 		}
 		cfg.Credentials = c
+		metadata = fromMetadata
+	}
+
+	// the environment only overrides a project that wasn't chosen for the
+	// app, such as the quota project of gcloud user credentials or the
+	// project of the instance
+	if cfg.ProjectID == "" && (metadata || cfg.Credentials.ProjectID == "" || cfg.Credentials.Type == userCredentialsType) {
+		projectID, err := projectIDOverride()
+		if err != nil {
+			return nil, err
+		}
+		cfg.ProjectID = projectID
+	}
+	if cfg.ProjectID != "" {
+		cfg.Credentials.ProjectID = cfg.ProjectID
+	}
+	if cfg.Credentials.ProjectID == "" {
+		return nil, errors.New("Must provide a project ID with WithProjectID, the credentials or " + projectEnv + ".")
+	}
+
+	var owned []closer
+	if cfg.KeySource == nil {
+		store := newCertificateStore(clientCertURL)
+		cfg.KeySource = store
+		owned = append(owned, store)
+	}
+	if cfg.SessionKeySource == nil {
+		store := newCertificateStore(sessionCookieCertURL)
+		cfg.SessionKeySource = store
+		owned = append(owned, store)
+	}
+
+	if cfg.Signer == nil && cfg.Credentials.PrivateKey != nil && cfg.Credentials.PrivateKeyID != "" {
+		ring := NewKeyRing(cfg.Credentials.ClientEmail)
+		ring.Add(cfg.Credentials.PrivateKeyID, cfg.Credentials.PrivateKey)
+		cfg.Signer = ring
+	}
+	if cfg.Signer == nil && cfg.Credentials.PrivateKey != nil {
+		cfg.Signer = &rsaSigner{
+			email: cfg.Credentials.ClientEmail,
+			key:   cfg.Credentials.PrivateKey,
+		}
+	}
+
+	// the emulator takes a fixed token in place of Google access tokens
+	if cfg.AccessTokenSource == nil && cfg.AuthEmulatorHost != "" {
+		cfg.AccessTokenSource = emulatorTokenSource{}
+	}
+	if cfg.AccessTokenSource == nil && cfg.Credentials.PrivateKey != nil {
+		cfg.AccessTokenSource = NewAccessTokenSource(cfg.Credentials)
+	}
+	if cfg.AccessTokenSource == nil && metadata {
+		cfg.AccessTokenSource = NewMetadataTokenSource()
+	}
+	if cfg.Signer == nil && metadata {
+		cfg.Signer = newIAMSigner(cfg.Credentials.ClientEmail, "", cfg.AccessTokenSource)
+	}
+
+	// an IAM signer without its own tokens uses the app's
+	if s, ok := cfg.Signer.(*iamSigner); ok && s.tokens == nil {
+		cfg.Signer = newIAMSigner(s.email, s.endpoint, cfg.AccessTokenSource)
+	}
+
+	if cfg.AuthEmulatorHost != "" && cfg.IdentityToolkitURL == identityToolkitURL {
+		cfg.IdentityToolkitURL = emulatorIdentityToolkitURL(cfg.AuthEmulatorHost)
+	}
+	if cfg.AuthEmulatorHost != "" && cfg.SecureTokenURL == secureTokenURL {
+		cfg.SecureTokenURL = emulatorSecureTokenURL(cfg.AuthEmulatorHost)
 	}
 
 	app := &App{
 		name:  cfg.Name,
 		creds: cfg.Credentials,
+
+		identityToolkitURL: cfg.IdentityToolkitURL,
+		secureTokenURL:     cfg.SecureTokenURL,
+		apiKey:             cfg.APIKey,
+		emulatorHost:       cfg.AuthEmulatorHost,
+
+		keys:        cfg.KeySource,
+		sessionKeys: cfg.SessionKeySource,
+		signer:      cfg.Signer,
+		tokens:      cfg.AccessTokenSource,
+		client:      cfg.HTTPClient,
+
+		owned: owned,
+	}
+	return app, nil
+}
+
+// Apps returns the registered apps, sorted by name.
+func Apps() []*App {
+	apps.RLock()
+	defer apps.RUnlock()
+
+	list := make([]*App, 0, len(apps.m))
+	for _, app := range apps.m {
+		list = append(list, app)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// Delete removes the app from the registry, so the name can be used again,
+// and stops the background refresh of the keys it created. Any further
+// calls using the app fail with ErrAppDeleted.
+func (a *App) Delete() error {
+	if !atomic.CompareAndSwapInt32(&a.deleted, 0, 1) {
+		return ErrAppDeleted
 	}
 
 	apps.Lock()
-	defer apps.Unlock()
+	if apps.m[a.name] == a {
+		delete(apps.m, a.name)
+	}
+	apps.Unlock()
 
-	if _, ok := apps.m[app.name]; ok {
-		return nil, fmt.Errorf("App %s already exists!", app.name)
+	for _, c := range a.owned {
+		c.close()
 	}
 
-	apps.m[app.name] = app
-	return app, nil
+	a.accountCerts.Lock()
+	for _, store := range a.accountCerts.m {
+		store.close()
+	}
+	a.accountCerts.m = nil
+	a.accountCerts.Unlock()
+
+	return nil
+}
+
+// context prepares the context for a call using the app, so that requests
+// fall back to the app's HTTP client. It returns ErrAppDeleted if the app
+// has been deleted.
+func (a *App) context(ctx context.Context) (context.Context, error) {
+	if atomic.LoadInt32(&a.deleted) != 0 {
+		return nil, ErrAppDeleted
+	}
+	return withAppClient(ctx, a.client), nil
+}
+
+// Auth returns the project-level Auth, configured with the options
+func (a *App) Auth(options ...VerifyOption) *Auth {
+	return a.TenantAuth("", options...)
+}
+
+// TenantAuth returns an Auth scoped to an Identity Platform tenant. Tokens
+// it verifies must belong to the tenant and custom tokens it creates will
+// sign users into the tenant.
+func (a *App) TenantAuth(tenantID string, options ...VerifyOption) *Auth {
+	auth := &Auth{
+		app:      a,
+		tenantID: tenantID,
+		verify:   defaultVerifyConfig(),
+	}
+	for _, option := range options {
+		option(&auth.verify)
+	}
+	return auth
+}
+
+// AccessTokenSource returns the source of OAuth2 access tokens for calling
+// Google admin REST APIs, or nil if the app has none. The source returns
+// ErrAppDeleted once the app is deleted.
+func (a *App) AccessTokenSource() AccessTokenSource {
+	if a.tokens == nil {
+		return nil
+	}
+	return appTokenSource{app: a}
+}
+
+// KeyRing returns the key ring custom tokens are signed with, so keys can be
+// rotated at runtime. It's created from the credentials private key and
+// private_key_id, or set with WithSigner, and is nil otherwise or once the
+// app is deleted.
+func (a *App) KeyRing() *KeyRing {
+	if atomic.LoadInt32(&a.deleted) != 0 {
+		return nil
+	}
+	ring, _ := a.signer.(*KeyRing)
+	return ring
+}
+
+// appTokenSource is the app's AccessTokenSource as handed out by the app,
+// which stops providing tokens when the app is deleted.
+type appTokenSource struct {
+	app *App
 }
 
-func (a *App) Auth() *Auth {
-	return &Auth{
-		app: a,
+// AccessToken implements AccessTokenSource.
+func (s appTokenSource) AccessToken(ctx context.Context) (*AccessToken, error) {
+	ctx, err := s.app.context(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return s.app.tokens.AccessToken(ctx)
 }
 
 func (a *App) Name() string {
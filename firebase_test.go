@@ -0,0 +1,125 @@
+package firebase
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestAppLifecycle(t *testing.T) {
+	creds := WithCredentials(&Credentials{
+		ProjectID:    testProjectID,
+		ClientEmail:  testClientEmail,
+		PrivateKeyID: testKeyID,
+		PrivateKey:   testKey,
+	})
+
+	app, err := New(WithName("lifecycle-test"), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(WithName("lifecycle-test"), creds); err == nil {
+		t.Error("expected error registering the name twice")
+	}
+
+	found := false
+	for _, a := range Apps() {
+		found = found || a == app
+	}
+	if !found {
+		t.Error("expected app to be listed")
+	}
+
+	// unregistered apps can share the name
+	other, err := NewApp(WithName("lifecycle-test"), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := GetApp("lifecycle-test"); a != app {
+		t.Error("expected the registered app")
+	}
+	if err := other.Delete(); err != nil {
+		t.Error(err)
+	}
+	if a, _ := GetApp("lifecycle-test"); a != app {
+		t.Error("expected deleting an unregistered app to leave the registered one")
+	}
+
+	tokens := app.AccessTokenSource()
+	if tokens == nil || app.KeyRing() == nil {
+		t.Fatal("expected an access token source and key ring")
+	}
+	if err := app.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Delete(); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+	if _, err := GetApp("lifecycle-test"); err == nil {
+		t.Error("expected deleted app to be unregistered")
+	}
+
+	ctx := context.Background()
+	if _, err := app.Auth().VerifyIDToken(ctx, testIDToken(t, "uid1", time.Now())); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+	if _, err := app.Auth().CreateCustomToken(ctx, "uid1", nil); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+	if _, err := app.Auth().IDTokenSource("refresh-token", "").Token(ctx); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+	if ring := app.KeyRing(); ring != nil {
+		t.Error("expected no key ring after delete")
+	}
+	if _, err := tokens.AccessToken(ctx); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+	if _, err := app.serviceAccountCerts(testClientEmail); err != ErrAppDeleted {
+		t.Errorf("expected ErrAppDeleted, got %v", err)
+	}
+
+	// the name can be used again
+	app, err = New(WithName("lifecycle-test"), creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.Delete()
+}
+
+func TestCertificateStoreClose(t *testing.T) {
+	block := make(chan struct{})
+	srv := testCertServer(t)
+	defer srv.Close()
+	body := testCertBody(t)
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		w.Write(body)
+	})
+	defer close(block)
+
+	store := newCertificateStore(srv.URL)
+	done := make(chan error)
+	go func() {
+		_, err := store.PublicKey(context.Background(), testKeyID)
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	store.close()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("expected error after close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("download not cancelled by close")
+	}
+
+	if _, err := store.PublicKey(context.Background(), testKeyID); err == nil {
+		t.Error("expected error after close")
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/captaincodeman/go-firebase
 
-go 1.12
+go 1.13
 
 require (
 	github.com/SermoDigital/jose v0.0.0-20180104203859-803625baeddc
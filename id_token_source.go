@@ -0,0 +1,187 @@
+package firebase
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Base URL for the Secure Token REST API
+	secureTokenURL = "https://securetoken.googleapis.com/v1"
+
+	// ID tokens are refreshed this long before they expire
+	idTokenRefreshAhead = 5 * time.Minute
+
+	// limit on how long a refresh can take
+	idTokenFetchTimeout = 30 * time.Second
+)
+
+type (
+	// IDTokenSource provides Firebase ID tokens for a user, exchanging the
+	// refresh token for a new ID token shortly before the current one
+	// expires. It's safe for concurrent use.
+	IDTokenSource struct {
+		mu       sync.Mutex
+		app      *App
+		endpoint string
+		apiKey   string
+
+		refreshToken string
+		idToken      string
+		expiry       time.Time
+
+		// fetch is the refresh in progress, if any
+		fetch *idTokenFetch
+	}
+
+	// idTokenFetch is a refresh shared by concurrent callers, which wait for
+	// done to be closed.
+	idTokenFetch struct {
+		done  chan struct{}
+		token string
+		err   error
+	}
+
+	// IDTokenTransport is an http.RoundTripper that authorizes requests with
+	// an ID token from the Source as an "Authorization: Bearer" header, for
+	// calling services protected by Auth.Authorize.
+	IDTokenTransport struct {
+		Source *IDTokenSource
+		// Base is the transport that makes the requests. It defaults to
+		// http.DefaultTransport.
+		Base http.RoundTripper
+	}
+)
+
+// IDTokenSource returns a source of ID tokens for the user with the refresh
+// token, such as the one returned by SignInWithCustomToken. The API key is
+// the project's Web API key; the key configured with WithAPIKey is used if
+// it's empty.
+func (a *Auth) IDTokenSource(refreshToken, apiKey string) *IDTokenSource {
+	if apiKey == "" {
+		apiKey = a.app.apiKey
+	}
+	return &IDTokenSource{
+		app:          a.app,
+		endpoint:     a.app.secureTokenURL + "/token",
+		apiKey:       apiKey,
+		refreshToken: refreshToken,
+	}
+}
+
+// Token returns a valid ID token, refreshing it if it's missing or about to
+// expire. Concurrent callers share a single refresh.
+func (s *IDTokenSource) Token(ctx context.Context) (string, error) {
+	ctx, err := s.app.context(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	if s.idToken != "" && clock.Now().Add(idTokenRefreshAhead).Before(s.expiry) {
+		token := s.idToken
+		s.mu.Unlock()
+		return token, nil
+	}
+	f := s.fetch
+	if f == nil {
+		f = &idTokenFetch{done: make(chan struct{})}
+		s.fetch = f
+		go s.refresh(ctx, f, s.refreshToken)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refresh exchanges the refresh token for a new ID token, storing the result
+// in the source and the fetch that waiting callers share.
+func (s *IDTokenSource) refresh(ctx context.Context, f *idTokenFetch, refreshToken string) {
+	// the refresh outlives a caller that gives up waiting, unless its
+	// client only works for the caller's request
+	if !requestScopedClient(ctx) {
+		ctx = detach(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, idTokenFetchTimeout)
+	defer cancel()
+
+	token, expiry, refreshToken, err := s.exchange(ctx, refreshToken)
+
+	s.mu.Lock()
+	if err == nil {
+		s.idToken = token
+		s.expiry = expiry
+		s.refreshToken = refreshToken
+	}
+	f.token, f.err = token, err
+	s.fetch = nil
+	s.mu.Unlock()
+
+	close(f.done)
+}
+
+// exchange calls the Secure Token API to exchange the refresh token for an
+// ID token, returning its expiry and the refresh token to use next time.
+func (s *IDTokenSource) exchange(ctx context.Context, refreshToken string) (string, time.Time, string, error) {
+	if refreshToken == "" {
+		return "", time.Time{}, "", errors.New("Refresh token must be provided.")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}
+	var resp struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    string `json:"expires_in"`
+	}
+
+	endpoint := s.endpoint
+	if s.apiKey != "" {
+		endpoint += "?key=" + url.QueryEscape(s.apiKey)
+	}
+	if err := postForm(ctx, endpoint, form, &resp); err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	expiresIn, err := strconv.ParseInt(resp.ExpiresIn, 10, 64)
+	if err != nil {
+		return "", time.Time{}, "", err
+	}
+
+	if resp.RefreshToken != "" {
+		refreshToken = resp.RefreshToken
+	}
+	return resp.IDToken, clock.Now().Add(time.Duration(expiresIn) * time.Second), refreshToken, nil
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *IDTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	r := cloneRequestWithHeader(req, "Authorization", "Bearer "+token)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,67 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestIDTokenSource(t *testing.T) {
+	var refreshes int32
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" || r.URL.Query().Get("key") != "api-key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh-token" {
+			t.Errorf("unexpected form %v", r.Form)
+		}
+		atomic.AddInt32(&refreshes, 1)
+		w.Write([]byte(`{"id_token":"id-token","refresh_token":"refresh-token","expires_in":"3600"}`))
+	}))
+	defer tokenSrv.Close()
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer id-token" {
+			t.Errorf("unexpected authorization %q", auth)
+		}
+	}))
+	defer apiSrv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, secureTokenURL: tokenSrv.URL, apiKey: "api-key"}
+	source := app.Auth().IDTokenSource("refresh-token", "")
+	client := &http.Client{Transport: &IDTokenTransport{Source: source}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(apiSrv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&refreshes); n != 1 {
+		t.Errorf("expected 1 refresh, got %d", n)
+	}
+
+	// the token is refreshed shortly before it expires
+	defer func(c tickTock) { clock = c }(clock)
+	clock = mockClock(time.Now().Add(56 * time.Minute))
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&refreshes); n != 2 {
+		t.Errorf("expected 2 refreshes, got %d", n)
+	}
+}
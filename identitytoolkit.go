@@ -0,0 +1,159 @@
+package firebase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Base URL for the Identity Toolkit REST API
+	identityToolkitURL = "https://identitytoolkit.googleapis.com/v1"
+)
+
+type (
+	// IdentityToolkitError is returned when an Identity Toolkit REST call
+	// fails. Message holds the error code reported by the API, such as
+	// USER_DISABLED or INVALID_ID_TOKEN.
+	IdentityToolkitError struct {
+		StatusCode int
+		Message    string
+	}
+)
+
+// identityToolkitErrors maps Identity Toolkit error codes to package errors.
+var identityToolkitErrors = map[string]error{
+	"EMAIL_EXISTS":                ErrEmailExists,
+	"EMAIL_NOT_FOUND":             ErrEmailNotFound,
+	"INVALID_EMAIL":               ErrInvalidEmail,
+	"INVALID_PASSWORD":            ErrInvalidPassword,
+	"INVALID_LOGIN_CREDENTIALS":   ErrInvalidCredentials,
+	"WEAK_PASSWORD":               ErrWeakPassword,
+	"INVALID_CUSTOM_TOKEN":        ErrInvalidCustomToken,
+	"CREDENTIAL_MISMATCH":         ErrInvalidCustomToken,
+	"OPERATION_NOT_ALLOWED":       ErrOperationNotAllowed,
+	"TOO_MANY_ATTEMPTS_TRY_LATER": ErrTooManyAttempts,
+	"USER_DISABLED":               ErrUserDisabled,
+	"USER_NOT_FOUND":              ErrUserNotFound,
+}
+
+func (e *IdentityToolkitError) Error() string {
+	return fmt.Sprintf("identity toolkit: %s (%d)", e.Message, e.StatusCode)
+}
+
+// Code returns the error code from the message, which may be followed by a
+// description, e.g. "WEAK_PASSWORD : Password should be at least 6 characters".
+func (e *IdentityToolkitError) Code() string {
+	code := e.Message
+	if i := strings.IndexAny(code, " :"); i >= 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// Is reports whether target is the package error for the error code, such as
+// ErrEmailExists for EMAIL_EXISTS.
+func (e *IdentityToolkitError) Is(target error) bool {
+	err, ok := identityToolkitErrors[e.Code()]
+	return ok && err == target
+}
+
+// identityToolkitEndpoint returns the URL for an Identity Toolkit method,
+// e.g. "accounts:lookup", including the API key when one is configured.
+func (a *App) identityToolkitEndpoint(method string) string {
+	return a.identityToolkitEndpointWithKey(method, a.apiKey)
+}
+
+// identityToolkitEndpointWithKey returns the URL for an Identity Toolkit
+// method using the API key given, or the configured key if it's empty.
+func (a *App) identityToolkitEndpointWithKey(method, apiKey string) string {
+	if apiKey == "" {
+		apiKey = a.apiKey
+	}
+	endpoint := a.identityToolkitURL + "/" + method
+	if apiKey != "" {
+		endpoint += "?key=" + url.QueryEscape(apiKey)
+	}
+	return endpoint
+}
+
+// postJSON posts the JSON encoded request to the endpoint and decodes the
+// JSON response into resp. Non-200 responses are returned as an
+// *IdentityToolkitError.
+func postJSON(ctx context.Context, endpoint string, req, resp interface{}) error {
+	return postAuthorizedJSON(ctx, nil, endpoint, req, resp)
+}
+
+// postAuthorizedJSON is like postJSON but authorizes the request with an
+// access token from the token source, if there is one.
+func postAuthorizedJSON(ctx context.Context, tokens AccessTokenSource, endpoint string, req, resp interface{}) error {
+	client, err := ContextClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var token string
+	if tokens != nil {
+		t, err := tokens.AccessToken(ctx)
+		if err != nil {
+			return err
+		}
+		token = t.Token
+	}
+
+	return post(ctx, client, endpoint, "application/json", token, body, resp)
+}
+
+// postForm posts the form to the endpoint and decodes the JSON response into
+// resp. Non-200 responses are returned as an *IdentityToolkitError.
+func postForm(ctx context.Context, endpoint string, form url.Values, resp interface{}) error {
+	client, err := ContextClient(ctx)
+	if err != nil {
+		return err
+	}
+	return post(ctx, client, endpoint, "application/x-www-form-urlencoded", "", []byte(form.Encode()), resp)
+}
+
+func post(ctx context.Context, client *http.Client, endpoint, contentType, token string, body []byte, resp interface{}) error {
+	hr, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hr.Header.Set("Content-Type", contentType)
+	if token != "" {
+		hr.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	r, err := client.Do(hr.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil || e.Error.Message == "" {
+			e.Error.Message = r.Status
+		}
+		return &IdentityToolkitError{
+			StatusCode: r.StatusCode,
+			Message:    e.Error.Message,
+		}
+	}
+
+	return json.NewDecoder(r.Body).Decode(resp)
+}
@@ -0,0 +1,162 @@
+package firebase
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// KeyRing holds the private keys of a service account during key
+	// rotation. Custom tokens are signed with the active key and, while the
+	// ring holds more than one key, carry its key ID. Tokens signed with
+	// any key still on the ring can be verified. Keys can be added,
+	// activated and retired at runtime.
+	//
+	// A KeyRing is a Signer and a KeySource, and is safe for concurrent use.
+	KeyRing struct {
+		mu     sync.RWMutex
+		email  string
+		keys   map[string]*rsa.PrivateKey
+		active string
+	}
+
+	// activeSigner is implemented by Signers that switch between keys, so
+	// that a token is signed with the key its "kid" header names.
+	activeSigner interface {
+		Active() (Signer, error)
+		// rotating reports whether there are several keys, so tokens
+		// need a "kid" header to tell which one signed them
+		rotating() bool
+	}
+)
+
+// NewKeyRing returns an empty KeyRing for the service account.
+func NewKeyRing(email string) *KeyRing {
+	return &KeyRing{
+		email: email,
+		keys:  make(map[string]*rsa.PrivateKey),
+	}
+}
+
+// Add puts the key on the ring under its private_key_id, replacing any key
+// with the same ID. The first key added becomes the active key.
+func (r *KeyRing) Add(kid string, key *rsa.PrivateKey) error {
+	if kid == "" {
+		return errors.New("Key ID must be provided.")
+	}
+	if key == nil {
+		return errors.New("Private key must be provided.")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[kid] = key
+	if r.active == "" {
+		r.active = kid
+	}
+	return nil
+}
+
+// Activate makes the key with the ID the one new tokens are signed with.
+func (r *KeyRing) Activate(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[kid]; !ok {
+		return &KeyNotFoundError{KeyID: kid}
+	}
+	r.active = kid
+	return nil
+}
+
+// Retire removes the key with the ID, so tokens signed with it no longer
+// verify. The active key can't be retired.
+func (r *KeyRing) Retire(kid string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.keys[kid]; !ok {
+		return &KeyNotFoundError{KeyID: kid}
+	}
+	if kid == r.active {
+		return fmt.Errorf("key ID %s is active and can't be retired", kid)
+	}
+	delete(r.keys, kid)
+	return nil
+}
+
+// KeyID returns the ID of the active key.
+func (r *KeyRing) KeyID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.active
+}
+
+// Active returns a Signer for the key that is active now, which keeps using
+// that key if another is activated. It fails if the ring has no keys.
+func (r *KeyRing) Active() (Signer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[r.active]
+	if !ok {
+		return nil, errors.New("Key ring has no active key")
+	}
+	return &rsaSigner{
+		email: r.email,
+		keyID: r.active,
+		key:   key,
+	}, nil
+}
+
+// rotating implements activeSigner.
+func (r *KeyRing) rotating() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.keys) > 1
+}
+
+// Sign implements Signer using the active key.
+func (r *KeyRing) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	s, err := r.Active()
+	if err != nil {
+		return nil, err
+	}
+	return s.Sign(ctx, data)
+}
+
+// Email implements Signer.
+func (r *KeyRing) Email() string {
+	return r.email
+}
+
+// PublicKey implements KeySource for the keys on the ring.
+func (r *KeyRing) PublicKey(ctx context.Context, kid string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[kid]
+	if !ok {
+		return nil, &KeyNotFoundError{KeyID: kid}
+	}
+	return &key.PublicKey, nil
+}
+
+// publicKeys returns the public keys of all the keys on the ring.
+func (r *KeyRing) publicKeys() []interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]interface{}, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, &key.PublicKey)
+	}
+	return keys
+}
@@ -0,0 +1,98 @@
+package firebase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+func TestKeyRing(t *testing.T) {
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring := NewKeyRing(testClientEmail)
+	ring.Add("old-key", testKey)
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, signer: ring}
+	auth := app.Auth()
+	ctx := context.Background()
+
+	mint := func() string {
+		s, err := auth.CreateCustomToken(ctx, "uid1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	kid := func(s string) interface{} {
+		token, err := jws.ParseJWT([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token.(jws.JWS).Protected().Get("kid")
+	}
+
+	// with a single key the key ID is opt-in
+	if k := kid(mint()); k != nil {
+		t.Errorf("expected no kid, got %v", k)
+	}
+
+	ring.Add("new-key", newKey)
+	if kid := ring.KeyID(); kid != "old-key" {
+		t.Errorf("expected first key to be active, got %s", kid)
+	}
+
+	oldToken := mint()
+	if k := kid(oldToken); k != "old-key" {
+		t.Errorf("expected kid old-key, got %v", k)
+	}
+
+	if err := ring.Activate("new-key"); err != nil {
+		t.Fatal(err)
+	}
+	newToken := mint()
+	if k := kid(newToken); k != "new-key" {
+		t.Errorf("expected kid new-key, got %v", k)
+	}
+
+	// tokens signed with either key verify during the overlap
+	for _, s := range []string{oldToken, newToken} {
+		if _, err := auth.VerifyCustomToken(ctx, s); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := ring.Retire("new-key"); err == nil {
+		t.Error("expected error retiring the active key")
+	}
+	if err := ring.Retire("old-key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.VerifyCustomToken(ctx, oldToken); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if _, err := auth.VerifyCustomToken(ctx, newToken); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeyRingEmpty(t *testing.T) {
+	app, err := NewApp(WithCredentials(&Credentials{ProjectID: testProjectID}), WithSigner(NewKeyRing(testClientEmail)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+
+	if _, err := app.Auth().CreateCustomToken(context.Background(), "uid1", nil); err == nil {
+		t.Error("expected error signing with an empty key ring")
+	}
+	if _, err := app.KeyRing().Sign(context.Background(), []byte("data")); err == nil {
+		t.Error("expected error signing with an empty key ring")
+	}
+}
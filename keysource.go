@@ -0,0 +1,104 @@
+package firebase
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// KeySource provides the public keys used to verify token signatures,
+	// looked up by the key ID in the token header.
+	KeySource interface {
+		PublicKey(ctx context.Context, kid string) (interface{}, error)
+	}
+
+	staticKeySource map[string]interface{}
+)
+
+// NewX509KeySource returns a KeySource that downloads and caches
+// certificates in the Google x509 metadata format, which is a JSON object
+// of key IDs to PEM encoded certificates.
+func NewX509KeySource(url string, options ...KeySourceOption) KeySource {
+	return newCertificateStore(url, options...)
+}
+
+// NewJWKSKeySource returns a KeySource that downloads and caches a JSON Web
+// Key Set. Only RSA keys are supported.
+func NewJWKSKeySource(url string, options ...KeySourceOption) KeySource {
+	return newKeyStore(url, parseJWKS, options...)
+}
+
+// NewStaticKeySource returns a KeySource for a fixed set of keys, which may
+// be public keys or *x509.Certificate values.
+func NewStaticKeySource(keys map[string]interface{}) KeySource {
+	s := make(staticKeySource, len(keys))
+	for kid, key := range keys {
+		s[kid] = publicKey(key)
+	}
+	return s
+}
+
+// PublicKey implements KeySource.
+func (s staticKeySource) PublicKey(ctx context.Context, kid string) (interface{}, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, &KeyNotFoundError{KeyID: kid}
+	}
+	return key, nil
+}
+
+// publicKey returns the public key from a certificate, or the key itself.
+func publicKey(key interface{}) interface{} {
+	if cert, ok := key.(*x509.Certificate); ok {
+		return cert.PublicKey
+	}
+	return key
+}
+
+// parseJWKS parses a JSON Web Key Set. The response has the format:
+//
+//	{
+//	  "keys": [
+//	    {"kty": "RSA", "kid": "kid1", "n": "...", "e": "AQAB", ...},
+//	  ]
+//	}
+func parseJWKS(r io.Reader) (map[string]interface{}, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("key ID %s has invalid modulus: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("key ID %s has invalid exponent: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
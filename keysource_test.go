@@ -0,0 +1,45 @@
+package firebase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestKeySources(t *testing.T) {
+	jwksSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(testKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(testKey.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwksSrv.Close()
+
+	sources := map[string]KeySource{
+		"jwks":   NewJWKSKeySource(jwksSrv.URL),
+		"static": NewStaticKeySource(map[string]interface{}{testKeyID: &testKey.PublicKey}),
+	}
+
+	ctx := context.Background()
+	token := testIDToken(t, "uid1", time.Now())
+
+	for name, keys := range sources {
+		app := &App{creds: &Credentials{ProjectID: testProjectID}, keys: keys}
+		if _, err := app.Auth().VerifyIDToken(ctx, token); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if _, err := keys.PublicKey(ctx, "unknown"); err == nil {
+			t.Errorf("%s: expected error for unknown key", name)
+		}
+	}
+}
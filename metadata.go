@@ -0,0 +1,128 @@
+package firebase
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Environment variable overriding the host:port of the metadata server
+	metadataHostEnv = "GCE_METADATA_HOST"
+
+	// Host of the Compute Engine metadata server
+	defaultMetadataHost = "metadata.google.internal"
+
+	// limit on how long to look for the metadata server
+	metadataTimeout = 3 * time.Second
+)
+
+type (
+	// metadataTokenSource gets access tokens for the instance's default
+	// service account from the metadata server.
+	metadataTokenSource struct {
+		cache  tokenCache
+		scopes []string
+	}
+)
+
+// metadataHost returns the host of the metadata server.
+func metadataHost() string {
+	if host := os.Getenv(metadataHostEnv); host != "" {
+		return host
+	}
+	return defaultMetadataHost
+}
+
+// metadataGet returns the metadata value at the path, such as
+// "project/project-id".
+func metadataGet(ctx context.Context, path string) (string, error) {
+	resp, err := metadataRequest(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Metadata-Flavor") != "Google" {
+		return "", fmt.Errorf("metadata %s fails: %s", path, resp.Status)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// metadataRequest gets the metadata at the path. The caller must close the
+// response body.
+func metadataRequest(ctx context.Context, path string) (*http.Response, error) {
+	client, err := ContextClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", "http://"+metadataHost()+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	return client.Do(req.WithContext(ctx))
+}
+
+// metadataCredentials returns the project and default service account of
+// the instance from the metadata server. There is no private key, so custom
+// tokens have to be signed some other way.
+func metadataCredentials(ctx context.Context) (*Credentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, metadataTimeout)
+	defer cancel()
+
+	projectID, err := metadataGet(ctx, "project/project-id")
+	if err != nil {
+		return nil, err
+	}
+	email, err := metadataGet(ctx, "instance/service-accounts/default/email")
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{
+		ProjectID:   projectID,
+		ClientEmail: email,
+	}, nil
+}
+
+// NewMetadataTokenSource returns an AccessTokenSource for the default
+// service account of a Compute Engine, Cloud Run or App Engine flexible
+// instance. Tokens are cached until shortly before they expire. The scopes
+// are only used where the platform allows them to be chosen.
+func NewMetadataTokenSource(scopes ...string) AccessTokenSource {
+	return &metadataTokenSource{scopes: scopes}
+}
+
+// AccessToken implements AccessTokenSource. Concurrent callers share a single
+// refresh.
+func (s *metadataTokenSource) AccessToken(ctx context.Context) (*AccessToken, error) {
+	return s.cache.get(ctx, s.fetch)
+}
+
+// fetch gets a new access token from the metadata server.
+func (s *metadataTokenSource) fetch(ctx context.Context) (*AccessToken, error) {
+	path := "instance/service-accounts/default/token"
+	if len(s.scopes) > 0 {
+		path += "?scopes=" + url.QueryEscape(strings.Join(s.scopes, ","))
+	}
+	resp, err := metadataRequest(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeAccessToken(resp)
+}
@@ -0,0 +1,61 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestMetadataCredentials(t *testing.T) {
+	var tokens int
+	metadataSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Metadata-Flavor", "Google")
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte(testProjectID))
+		case "/computeMetadata/v1/instance/service-accounts/default/email":
+			w.Write([]byte(testClientEmail))
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			tokens++
+			w.Write([]byte(`{"access_token":"metadata-token","expires_in":3600,"token_type":"Bearer"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer metadataSrv.Close()
+
+	defer setenv(map[string]string{
+		metadataHostEnv: strings.TrimPrefix(metadataSrv.URL, "http://"),
+	})()
+
+	app, err := New(WithName("metadata-test"), WithMetadataCredentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+	if app.creds.ProjectID != testProjectID || app.creds.ClientEmail != testClientEmail {
+		t.Errorf("unexpected credentials %+v", app.creds)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		token, err := app.AccessTokenSource().AccessToken(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Token != "metadata-token" {
+			t.Errorf("unexpected token %s", token.Token)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("expected 1 token request, got %d", tokens)
+	}
+
+	signer, ok := app.signer.(*iamSigner)
+	if !ok || signer.email != testClientEmail || signer.tokens != app.tokens {
+		t.Errorf("expected IAM signer with metadata tokens, got %#v", app.signer)
+	}
+}
@@ -4,10 +4,16 @@ import (
 	"fmt"
 
 	"net/http"
+
+	"golang.org/x/net/context"
 )
 
 const bearer = "Bearer"
 
+// SessionCookieName is the name of the cookie holding the session cookie
+// created by CreateSessionCookie.
+var SessionCookieName = "session"
+
 type AuthFunc func(*Token) (bool, error)
 
 func AuthorizationFromParam(req *http.Request) (string, error) {
@@ -39,9 +45,29 @@ func AuthorizationFromRequest(req *http.Request) (string, error) {
 	return authorization, nil
 }
 
+func AuthorizationFromCookie(req *http.Request) (string, error) {
+	cookie, err := req.Cookie(SessionCookieName)
+	if err != nil {
+		return "", fmt.Errorf("Session cookie not found")
+	}
+	return cookie.Value, nil
+}
+
 func (a *Auth) Authorize(h http.Handler, authFn AuthFunc) http.Handler {
+	return a.authorize(h, authFn, AuthorizationFromRequest, a.VerifyIDToken)
+}
+
+// AuthorizeSession is like Authorize but checks the session cookie rather
+// than an ID token, for pages where the browser can't send bearer headers.
+func (a *Auth) AuthorizeSession(h http.Handler, authFn AuthFunc) http.Handler {
+	return a.authorize(h, authFn, AuthorizationFromCookie, a.VerifySessionCookie)
+}
+
+type verifyFunc func(context.Context, string) (*Token, error)
+
+func (a *Auth) authorize(h http.Handler, authFn AuthFunc, from func(*http.Request) (string, error), verify verifyFunc) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		authorization, err := AuthorizationFromRequest(r)
+		authorization, err := from(r)
 		if err != nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
@@ -54,9 +80,10 @@ func (a *Auth) Authorize(h http.Handler, authFn AuthFunc) http.Handler {
 			return
 		}
 
-		token, err := a.VerifyIDToken(ctx, authorization)
+		token, err := verify(ctx, authorization)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status := errorStatus(err)
+			http.Error(w, http.StatusText(status), status)
 			return
 		}
 
@@ -85,39 +112,45 @@ func (a *Auth) Authenticated(h http.Handler, roles ...string) http.Handler {
 
 func (a *Auth) AnyRole(h http.Handler, roles ...string) http.Handler {
 	return a.Authorize(h, func(token *Token) (bool, error) {
-		authorized := false
-		claimedRoles := token.Claims().Get("roles").([]interface{})
+		claimedRoles, err := tokenRoles(token)
+		if err != nil {
+			return false, err
+		}
 		for _, role := range roles {
-			for _, claimedRole := range claimedRoles {
-				if claimedRole.(string) == role {
-					authorized = true
-					break
-				}
-			}
-			if authorized {
-				break
+			if claimedRoles[role] {
+				return true, nil
 			}
 		}
-		return authorized, nil
+		return false, nil
 	})
 }
 
 func (a *Auth) AllRoles(h http.Handler, roles ...string) http.Handler {
 	return a.Authorize(h, func(token *Token) (bool, error) {
-		claimedRoles := token.Claims().Get("roles").([]interface{})
+		claimedRoles, err := tokenRoles(token)
+		if err != nil {
+			return false, err
+		}
 		for _, role := range roles {
-			authorized := false
-			for _, claimedRole := range claimedRoles {
-				if claimedRole.(string) == role {
-					authorized = true
-					break
-				}
-			}
-			if !authorized {
+			if !claimedRoles[role] {
 				return false, nil
 			}
 		}
-
 		return true, nil
 	})
 }
+
+// tokenRoles returns the set of roles in the token's custom "roles" claim.
+func tokenRoles(token *Token) (map[string]bool, error) {
+	var claims struct {
+		Roles []string `json:"roles"`
+	}
+	if err := token.DecodeClaims(&claims); err != nil {
+		return nil, err
+	}
+	roles := make(map[string]bool, len(claims.Roles))
+	for _, role := range claims.Roles {
+		roles[role] = true
+	}
+	return roles, nil
+}
@@ -0,0 +1,105 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthorizeStatus(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, keys: NewX509KeySource(certSrv.URL)}
+	h := app.Auth().Authenticated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	expired := testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	})
+	otherProject := testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+		"aud": "other-project",
+	})
+	unknownKey := testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), nil)
+	// the last character of the signature partly encodes padding bits, so
+	// the one before it is changed
+	i, c := len(unknownKey)-2, "A"
+	if unknownKey[i] == 'A' {
+		c = "B"
+	}
+	unknownKey = unknownKey[:i] + c + unknownKey[i+1:]
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid", testIDToken(t, "uid1", time.Now()), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"malformed", "not-a-token", http.StatusBadRequest},
+		{"expired", expired, http.StatusUnauthorized},
+		{"audience", otherProject, http.StatusUnauthorized},
+		{"signature", unknownKey, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, w.Code)
+		}
+	}
+
+	// the keys can't be fetched
+	app.keys = newCertificateStore(certSrv.URL)
+	certSrv.Config.Handler = http.NotFoundHandler()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+testIDToken(t, "uid1", time.Now()))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	auth := (&App{creds: &Credentials{ProjectID: testProjectID}, keys: NewX509KeySource(certSrv.URL)}).Auth()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	token := func(roles interface{}) string {
+		return testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+			"roles": roles,
+		})
+	}
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		token   string
+		status  int
+	}{
+		{"any", auth.AnyRole(ok, "admin", "operator"), token([]string{"operator"}), http.StatusOK},
+		{"any missing", auth.AnyRole(ok, "admin"), token([]string{"operator"}), http.StatusUnauthorized},
+		{"all", auth.AllRoles(ok, "admin", "operator"), token([]string{"operator", "admin"}), http.StatusOK},
+		{"all missing", auth.AllRoles(ok, "admin", "operator"), token([]string{"operator"}), http.StatusUnauthorized},
+		{"no roles", auth.AnyRole(ok, "admin"), testIDToken(t, "uid1", time.Now()), http.StatusUnauthorized},
+		{"invalid roles", auth.AnyRole(ok, "admin"), token([]int{1}), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+test.token)
+		w := httptest.NewRecorder()
+		test.handler.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, w.Code)
+		}
+	}
+}
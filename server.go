@@ -93,7 +93,8 @@ func (s *Server) generateHandler(w http.ResponseWriter, r *http.Request) {
 	// check that it's valid
 	token, err := s.auth.VerifyIDToken(ctx, authorization)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		status := errorStatus(err)
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
 
@@ -108,7 +109,7 @@ func (s *Server) generateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// mint a custom token
-	tokenString, err := s.auth.CreateCustomToken(userID, claims)
+	tokenString, err := s.auth.CreateCustomToken(ctx, userID, claims)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -130,7 +131,8 @@ func (s *Server) verifyHandler(w http.ResponseWriter, r *http.Request) {
 	// check that it's valid
 	token, err := s.auth.VerifyIDToken(ctx, authorization)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		status := errorStatus(err)
+		http.Error(w, http.StatusText(status), status)
 		return
 	}
 
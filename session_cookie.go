@@ -0,0 +1,59 @@
+package firebase
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Issuer prefix for Firebase Auth session cookies
+	sessionCookieIssuerPrefix = "https://session.firebase.google.com/"
+
+	// Allowed range for the session cookie duration
+	minSessionCookieDuration = 5 * time.Minute
+	maxSessionCookieDuration = 14 * 24 * time.Hour
+)
+
+// CreateSessionCookie exchanges a valid ID token for a session cookie that
+// expires after the given duration, which must be between 5 minutes and
+// 2 weeks.
+func (a *Auth) CreateSessionCookie(ctx context.Context, idToken string, expiresIn time.Duration) (string, error) {
+	ctx, err := a.app.context(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if idToken == "" {
+		return "", errors.New("ID Token must be provided.")
+	}
+	if expiresIn < minSessionCookieDuration || expiresIn > maxSessionCookieDuration {
+		return "", errors.New("Session cookie duration must be between 5 minutes and 2 weeks")
+	}
+
+	req := struct {
+		IDToken       string `json:"idToken"`
+		ValidDuration int64  `json:"validDuration"`
+		TenantID      string `json:"tenantId,omitempty"`
+	}{
+		IDToken:       idToken,
+		ValidDuration: int64(expiresIn / time.Second),
+		TenantID:      a.tenantID,
+	}
+	var resp struct {
+		SessionCookie string `json:"sessionCookie"`
+	}
+
+	method := "projects/" + a.app.creds.ProjectID + ":createSessionCookie"
+	if err := postAuthorizedJSON(ctx, a.app.tokens, a.app.identityToolkitEndpoint(method), req, &resp); err != nil {
+		return "", err
+	}
+	return resp.SessionCookie, nil
+}
+
+// VerifySessionCookie verifies a session cookie created by
+// CreateSessionCookie and returns the decoded token.
+func (a *Auth) VerifySessionCookie(ctx context.Context, cookie string) (*Token, error) {
+	return a.verifyToken(ctx, cookie, "session cookie", a.app.sessionKeys, sessionCookieIssuerPrefix+a.app.creds.ProjectID)
+}
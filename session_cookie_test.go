@@ -0,0 +1,63 @@
+package firebase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSessionCookie(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	cookie := testToken(t, sessionCookieIssuerPrefix+testProjectID, "uid1", time.Now(), nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/"+testProjectID+":createSessionCookie" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var req struct {
+			ValidDuration int64 `json:"validDuration"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ValidDuration != 3600 {
+			t.Errorf("expected validDuration 3600, got %d", req.ValidDuration)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"sessionCookie": cookie})
+	}))
+	defer srv.Close()
+
+	app := &App{
+		creds:              &Credentials{ProjectID: testProjectID},
+		identityToolkitURL: srv.URL,
+		sessionKeys:        NewX509KeySource(certSrv.URL),
+	}
+	auth := app.Auth()
+	ctx := context.Background()
+
+	if _, err := auth.CreateSessionCookie(ctx, "id-token", time.Minute); err == nil {
+		t.Error("expected error for short duration")
+	}
+
+	created, err := auth.CreateSessionCookie(ctx, "id-token", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := auth.VerifySessionCookie(ctx, created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, _ := token.UID(); uid != "uid1" {
+		t.Errorf("expected uid1, got %s", uid)
+	}
+
+	// an ID token has the wrong issuer for a session cookie
+	if _, err := auth.VerifySessionCookie(ctx, testIDToken(t, "uid1", time.Now())); err == nil {
+		t.Error("expected ID token to be rejected as a session cookie")
+	}
+}
@@ -0,0 +1,126 @@
+package firebase
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// SignInResult holds the tokens returned when a user signs in.
+	SignInResult struct {
+		// IDToken is the Firebase ID token for the user.
+		IDToken string
+		// RefreshToken can be exchanged for new ID tokens.
+		RefreshToken string
+		// Expiry is when the ID token expires.
+		Expiry time.Time
+		// UID is the uid of the user.
+		UID string
+		// Email is the email address of the user, for password sign-in.
+		Email string
+	}
+
+	// signInResponse is the response from the Identity Toolkit sign-in
+	// methods.
+	signInResponse struct {
+		IDToken      string `json:"idToken"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    string `json:"expiresIn"`
+		LocalID      string `json:"localId"`
+		Email        string `json:"email"`
+	}
+)
+
+// SignInWithCustomToken exchanges a custom token for an ID token and refresh
+// token, as a client would with signInWithCustomToken. The API key is the
+// project's Web API key; the key configured with WithAPIKey is used if it's
+// empty. Use WithIdentityToolkitURL or WithAuthEmulator to sign in against
+// another host.
+func (a *Auth) SignInWithCustomToken(ctx context.Context, customToken, apiKey string) (*SignInResult, error) {
+	ctx, err := a.app.context(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if customToken == "" {
+		return nil, errors.New("Custom token must be provided.")
+	}
+
+	req := struct {
+		Token             string `json:"token"`
+		ReturnSecureToken bool   `json:"returnSecureToken"`
+		TenantID          string `json:"tenantId,omitempty"`
+	}{
+		Token:             customToken,
+		ReturnSecureToken: true,
+		TenantID:          a.tenantID,
+	}
+	var resp signInResponse
+
+	endpoint := a.app.identityToolkitEndpointWithKey("accounts:signInWithCustomToken", apiKey)
+	if err := postJSON(ctx, endpoint, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.result()
+}
+
+// SignUpWithPassword creates a user with the email address and password and
+// signs them in. Use errors.Is to check for errors such as ErrEmailExists or
+// ErrWeakPassword. The API key is used as for SignInWithCustomToken.
+func (a *Auth) SignUpWithPassword(ctx context.Context, email, password, apiKey string) (*SignInResult, error) {
+	return a.passwordSignIn(ctx, "accounts:signUp", email, password, apiKey)
+}
+
+// SignInWithPassword signs in the user with the email address and password.
+// Use errors.Is to check for errors such as ErrEmailNotFound or
+// ErrInvalidPassword. The API key is used as for SignInWithCustomToken.
+func (a *Auth) SignInWithPassword(ctx context.Context, email, password, apiKey string) (*SignInResult, error) {
+	return a.passwordSignIn(ctx, "accounts:signInWithPassword", email, password, apiKey)
+}
+
+func (a *Auth) passwordSignIn(ctx context.Context, method, email, password, apiKey string) (*SignInResult, error) {
+	ctx, err := a.app.context(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if email == "" || password == "" {
+		return nil, errors.New("Email and password must be provided.")
+	}
+
+	req := struct {
+		Email             string `json:"email"`
+		Password          string `json:"password"`
+		ReturnSecureToken bool   `json:"returnSecureToken"`
+		TenantID          string `json:"tenantId,omitempty"`
+	}{
+		Email:             email,
+		Password:          password,
+		ReturnSecureToken: true,
+		TenantID:          a.tenantID,
+	}
+	var resp signInResponse
+
+	if err := postJSON(ctx, a.app.identityToolkitEndpointWithKey(method, apiKey), req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.result()
+}
+
+// result converts the response, with the expiry relative to now.
+func (r *signInResponse) result() (*SignInResult, error) {
+	expiresIn, err := strconv.ParseInt(r.ExpiresIn, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &SignInResult{
+		IDToken:      r.IDToken,
+		RefreshToken: r.RefreshToken,
+		Expiry:       clock.Now().Add(time.Duration(expiresIn) * time.Second),
+		UID:          r.LocalID,
+		Email:        r.Email,
+	}, nil
+}
@@ -0,0 +1,91 @@
+package firebase
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSignInWithCustomToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/accounts:signInWithCustomToken" || r.URL.Query().Get("key") != "api-key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var req struct {
+			Token             string `json:"token"`
+			ReturnSecureToken bool   `json:"returnSecureToken"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Token != "custom-token" || !req.ReturnSecureToken {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		w.Write([]byte(`{"idToken":"id-token","refreshToken":"refresh-token","expiresIn":"3600","localId":"uid1"}`))
+	}))
+	defer srv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, identityToolkitURL: srv.URL}
+	result, err := app.Auth().SignInWithCustomToken(context.Background(), "custom-token", "api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.IDToken != "id-token" || result.RefreshToken != "refresh-token" || result.UID != "uid1" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if d := result.Expiry.Sub(time.Now()); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected expiry in 1 hour, got %s", d)
+	}
+}
+
+func TestPasswordSignIn(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch {
+		case r.URL.Path == "/accounts:signUp" && req.Email == "taken@example.com":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":400,"message":"EMAIL_EXISTS"}}`))
+		case r.URL.Path == "/accounts:signUp" && req.Password == "weak":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":400,"message":"WEAK_PASSWORD : Password should be at least 6 characters"}}`))
+		case r.URL.Path == "/accounts:signInWithPassword" && req.Password != "password":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":400,"message":"INVALID_PASSWORD"}}`))
+		default:
+			w.Write([]byte(`{"idToken":"id-token","refreshToken":"refresh-token","expiresIn":"3600","localId":"uid1","email":"` + req.Email + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	auth := (&App{creds: &Credentials{ProjectID: testProjectID}, identityToolkitURL: srv.URL}).Auth()
+	ctx := context.Background()
+
+	result, err := auth.SignUpWithPassword(ctx, "user@example.com", "password", "api-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.UID != "uid1" || result.Email != "user@example.com" || result.IDToken != "id-token" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := auth.SignInWithPassword(ctx, "user@example.com", "password", "api-key"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := auth.SignUpWithPassword(ctx, "taken@example.com", "password", "api-key"); !errors.Is(err, ErrEmailExists) {
+		t.Errorf("expected ErrEmailExists, got %v", err)
+	}
+	if _, err := auth.SignUpWithPassword(ctx, "user@example.com", "weak", "api-key"); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("expected ErrWeakPassword, got %v", err)
+	}
+	if _, err := auth.SignInWithPassword(ctx, "user@example.com", "wrong", "api-key"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("expected ErrInvalidPassword, got %v", err)
+	}
+}
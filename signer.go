@@ -0,0 +1,142 @@
+package firebase
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Base URL for the IAM Credentials REST API
+	iamCredentialsURL = "https://iamcredentials.googleapis.com/v1"
+)
+
+type (
+	// Signer signs custom tokens on behalf of a service account. Sign must
+	// return an RSA SHA-256 (RS256) signature of the data.
+	Signer interface {
+		Sign(ctx context.Context, data []byte) ([]byte, error)
+		Email() string
+	}
+
+	// keyIDer is implemented by Signers that know the ID of their key.
+	keyIDer interface {
+		KeyID() string
+	}
+
+	rsaSigner struct {
+		email string
+		keyID string
+		key   *rsa.PrivateKey
+	}
+
+	iamSigner struct {
+		email    string
+		endpoint string
+		tokens   AccessTokenSource
+	}
+)
+
+// NewRSASigner returns a Signer that signs locally with the service account
+// private key.
+func NewRSASigner(email string, key *rsa.PrivateKey) Signer {
+	return &rsaSigner{
+		email: email,
+		key:   key,
+	}
+}
+
+func (s *rsaSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	h := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h[:])
+}
+
+func (s *rsaSigner) Email() string {
+	return s.email
+}
+
+func (s *rsaSigner) KeyID() string {
+	return s.keyID
+}
+
+// NewIAMSigner returns a Signer that uses the IAM Credentials signBlob API
+// to sign as the service account, so no private key is needed. The endpoint
+// defaults to the IAM Credentials API when empty.
+//
+// The HTTP client returned by ContextClient must be authorized to call the
+// API with a principal granted the Service Account Token Creator role, or
+// else the app's access tokens are used when it's passed to WithSigner.
+func NewIAMSigner(email, endpoint string) Signer {
+	return newIAMSigner(email, endpoint, nil)
+}
+
+// NewIAMSignerWithTokenSource is like NewIAMSigner but authorizes the calls
+// with access tokens from the token source.
+func NewIAMSignerWithTokenSource(email, endpoint string, tokens AccessTokenSource) Signer {
+	return newIAMSigner(email, endpoint, tokens)
+}
+
+func newIAMSigner(email, endpoint string, tokens AccessTokenSource) *iamSigner {
+	if endpoint == "" {
+		endpoint = iamCredentialsURL
+	}
+	return &iamSigner{
+		email:    email,
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		tokens:   tokens,
+	}
+}
+
+func (s *iamSigner) Sign(ctx context.Context, data []byte) ([]byte, error) {
+	req := struct {
+		Payload string `json:"payload"`
+	}{
+		Payload: base64.StdEncoding.EncodeToString(data),
+	}
+	var resp struct {
+		KeyID      string `json:"keyId"`
+		SignedBlob string `json:"signedBlob"`
+	}
+
+	endpoint := s.endpoint + "/projects/-/serviceAccounts/" + s.email + ":signBlob"
+	if err := postAuthorizedJSON(ctx, s.tokens, endpoint, req, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.SignedBlob)
+}
+
+func (s *iamSigner) Email() string {
+	return s.email
+}
+
+// ContextSignerFunc is a func which tries to return a Signer given a
+// Context value. If it returns an error, the search stops with that
+// error.  If it returns (nil, nil), the search continues down the list
+// of registered funcs.
+type ContextSignerFunc func(context.Context) (Signer, error)
+
+var contextSignerFuncs []ContextSignerFunc
+
+func RegisterContextSignerFunc(fn ContextSignerFunc) {
+	contextSignerFuncs = append(contextSignerFuncs, fn)
+}
+
+// ContextSigner returns the Signer provided by the platform for the context,
+// or nil if there isn't one. It's used when the App has no Signer of its own.
+func ContextSigner(ctx context.Context) (Signer, error) {
+	for _, fn := range contextSignerFuncs {
+		s, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
@@ -1,6 +1,9 @@
 package firebase
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/SermoDigital/jose/jwt"
 )
 
@@ -44,3 +47,54 @@ func (t *Token) IsEmailVerified() (bool, bool) {
 	emailVerified, ok := t.Claims().Get("email_verified").(bool)
 	return emailVerified, ok
 }
+
+// FirebaseInfo is the "firebase" claim added to ID tokens by Firebase Auth.
+type FirebaseInfo struct {
+	SignInProvider         string              `json:"sign_in_provider"`
+	Identities             map[string][]string `json:"identities"`
+	Tenant                 string              `json:"tenant"`
+	SignInSecondFactor     string              `json:"sign_in_second_factor"`
+	SecondFactorIdentifier string              `json:"second_factor_identifier"`
+}
+
+// Firebase returns the sign-in details from the "firebase" claim.
+func (t *Token) Firebase() (*FirebaseInfo, bool) {
+	claim, ok := t.Claims().Get("firebase").(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	b, err := json.Marshal(claim)
+	if err != nil {
+		return nil, false
+	}
+	var info FirebaseInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, false
+	}
+	return &info, true
+}
+
+// AuthTime returns the time the user authenticated.
+func (t *Token) AuthTime() (time.Time, bool) {
+	return t.Claims().GetTime("auth_time")
+}
+
+// IssuedAt returns the time the token was issued.
+func (t *Token) IssuedAt() (time.Time, bool) {
+	return t.Claims().IssuedAt()
+}
+
+// Expires returns the time the token expires.
+func (t *Token) Expires() (time.Time, bool) {
+	return t.Claims().Expiration()
+}
+
+// DecodeClaims unmarshals the token claims into v, which should be a pointer
+// to a struct with json tags for the custom claims it's interested in.
+func (t *Token) DecodeClaims(v interface{}) error {
+	b, err := json.Marshal(t.Claims())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -1,6 +1,7 @@
 package firebase
 
 import (
+	stdcrypto "crypto"
 	"errors"
 	"fmt"
 	"sort"
@@ -8,10 +9,14 @@ import (
 
 	"github.com/SermoDigital/jose/crypto"
 	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
 )
 
 const (
 	firebaseAudience = "https://identitytoolkit.googleapis.com/google.identity.identitytoolkit.v1.IdentityToolkit"
+
+	// Firebase rejects custom tokens valid for longer
+	maxCustomTokenExpiry = time.Hour
 )
 
 var (
@@ -39,9 +44,95 @@ func init() {
 	sort.Strings(reservedNames)
 }
 
-func (a *Auth) CreateCustomToken(uid string, developerClaims *Claims) (string, error) {
+// CustomTokenOptions configures the token minted by
+// CreateCustomTokenWithOptions.
+type CustomTokenOptions struct {
+	// Claims are the developer claims made available to security rules.
+	Claims *Claims
+
+	// Expires is how long the token is valid for. It defaults to, and can be
+	// no longer than, one hour.
+	Expires time.Duration
+
+	// TenantID signs the user in to an Identity Platform tenant. It defaults
+	// to the tenant of a tenant-scoped Auth.
+	TenantID string
+
+	// IncludeKeyID adds a "kid" header with the service account
+	// private_key_id. The Signer must know the key ID.
+	IncludeKeyID bool
+
+	// SigningMethod overrides the RS256 algorithm for non-Firebase uses of
+	// the token. It requires a Signer with a local RSA private key.
+	SigningMethod crypto.SigningMethod
+
+	// Headers are added to the token header.
+	Headers map[string]interface{}
+}
+
+// CreateCustomToken mints a custom token for the uid, signed by the app's
+// Signer or else the one registered for the context, which the client can
+// use to sign in with signInWithCustomToken.
+func (a *Auth) CreateCustomToken(ctx context.Context, uid string, developerClaims *Claims) (string, error) {
+	return a.CreateCustomTokenWithOptions(ctx, uid, &CustomTokenOptions{Claims: developerClaims})
+}
+
+// CreateCustomTokenWithOptions is like CreateCustomToken but allows the
+// expiry, tenant and headers of the token to be set.
+func (a *Auth) CreateCustomTokenWithOptions(ctx context.Context, uid string, options *CustomTokenOptions) (string, error) {
+	ctx, err := a.app.context(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if options == nil {
+		options = &CustomTokenOptions{}
+	}
+
+	signer := a.app.signer
+	if signer == nil && !a.app.IsEmulator() {
+		var err error
+		if signer, err = ContextSigner(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	// a key ring signs with the key that is active now, and names it when
+	// there are several keys it could be
+	includeKeyID := options.IncludeKeyID
+	if as, ok := signer.(activeSigner); ok {
+		includeKeyID = includeKeyID || as.rotating()
+		if signer, err = as.Active(); err != nil {
+			return "", err
+		}
+	}
+
 	issuer := a.app.creds.ClientEmail
-	privateKey := a.app.creds.PrivateKey
+	if signer != nil {
+		issuer = signer.Email()
+	}
+
+	// the emulator accepts unsigned tokens
+	var method crypto.SigningMethod
+	var key interface{}
+	switch {
+	case a.app.IsEmulator():
+		method = crypto.Unsecured
+		if issuer == "" {
+			issuer = emulatorServiceAccount
+		}
+	case signer == nil:
+		return "", errors.New("Must provide a signer or credentials with a private key.")
+	case options.SigningMethod != nil && options.SigningMethod.Alg() != crypto.SigningMethodRS256.Alg():
+		rs, ok := signer.(*rsaSigner)
+		if !ok {
+			return "", fmt.Errorf("Signing method %s requires a local private key", options.SigningMethod.Alg())
+		}
+		method = options.SigningMethod
+		key = rs.key
+	default:
+		method = &signerMethod{ctx: ctx, signer: signer}
+	}
 
 	if uid == "" {
 		return "", errors.New("Uid must be provided.")
@@ -53,27 +144,58 @@ func (a *Auth) CreateCustomToken(uid string, developerClaims *Claims) (string, e
 		return "", errors.New("Uid must be shorter than 128 characters")
 	}
 
+	expires := options.Expires
+	if expires == 0 {
+		expires = maxCustomTokenExpiry
+	}
+	if expires < 0 || expires > maxCustomTokenExpiry {
+		return "", errors.New("Expiry must be between 0 and 1 hour")
+	}
+
+	tenantID := a.tenantID
+	if options.TenantID != "" {
+		if tenantID != "" && tenantID != options.TenantID {
+			return "", fmt.Errorf("Tenant ID %s does not match the Auth tenant %s", options.TenantID, tenantID)
+		}
+		tenantID = options.TenantID
+	}
+
 	now := clock.Now()
-	method := crypto.SigningMethodRS256
 	claims := jws.Claims{}
 	claims.Set("uid", uid)
 	claims.SetIssuer(issuer)
 	claims.SetSubject(issuer)
 	claims.SetAudience(firebaseAudience)
 	claims.SetIssuedAt(now)
-	claims.SetExpiration(now.Add(time.Hour))
+	claims.SetExpiration(now.Add(expires))
+	if tenantID != "" {
+		claims.Set("tenant_id", tenantID)
+	}
 
-	if developerClaims != nil {
-		for claim := range *developerClaims {
-			if isReserved(claim) {
-				return "", fmt.Errorf("developer_claims cannot contain a reserved key: %s", claim)
-			}
+	if developerClaims := options.Claims; developerClaims != nil {
+		if err := developerClaims.Validate(); err != nil {
+			return "", err
 		}
 		claims.Set("claims", developerClaims)
 	}
 
 	jwt := jws.NewJWT(claims, method)
-	bytes, err := jwt.Serialize(privateKey)
+	header := jwt.(jws.JWS).Protected()
+	for name, value := range options.Headers {
+		if name == "alg" {
+			return "", errors.New("Headers cannot override the signing algorithm")
+		}
+		header.Set(name, value)
+	}
+	if includeKeyID && !a.app.IsEmulator() {
+		k, ok := signer.(keyIDer)
+		if !ok || k.KeyID() == "" {
+			return "", errors.New("Signer does not have a key ID")
+		}
+		header.Set("kid", k.KeyID())
+	}
+
+	bytes, err := jwt.Serialize(key)
 	if err != nil {
 		return "", err
 	}
@@ -81,6 +203,33 @@ func (a *Auth) CreateCustomToken(uid string, developerClaims *Claims) (string, e
 	return string(bytes), nil
 }
 
+// signerMethod adapts a Signer to a jose signing method so that jws can
+// serialize the token. It can only sign.
+type signerMethod struct {
+	ctx    context.Context
+	signer Signer
+}
+
+func (m *signerMethod) Alg() string {
+	return crypto.SigningMethodRS256.Alg()
+}
+
+func (m *signerMethod) Sign(raw []byte, key interface{}) (crypto.Signature, error) {
+	sig, err := m.signer.Sign(m.ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Signature(sig), nil
+}
+
+func (m *signerMethod) Verify(raw []byte, sig crypto.Signature, key interface{}) error {
+	return errors.New("signer cannot verify signatures")
+}
+
+func (m *signerMethod) Hasher() stdcrypto.Hash {
+	return crypto.SigningMethodRS256.Hasher()
+}
+
 // isReserved determines whether a given name is a reserved name via binary search.
 func isReserved(name string) bool {
 	if len(reservedNames) > 0 {
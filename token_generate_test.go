@@ -0,0 +1,131 @@
+package firebase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+const testClientEmail = "firebase-adminsdk@test-project.iam.gserviceaccount.com"
+
+func TestCreateCustomTokenSigners(t *testing.T) {
+	iamSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/-/serviceAccounts/"+testClientEmail+":signBlob" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		var req struct {
+			Payload string `json:"payload"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		payload, _ := base64.StdEncoding.DecodeString(req.Payload)
+		sig, err := NewRSASigner(testClientEmail, testKey).Sign(r.Context(), payload)
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"keyId":      testKeyID,
+			"signedBlob": base64.StdEncoding.EncodeToString(sig),
+		})
+	}))
+	defer iamSrv.Close()
+
+	signers := map[string]Signer{
+		"rsa": NewRSASigner(testClientEmail, testKey),
+		"iam": NewIAMSigner(testClientEmail, iamSrv.URL),
+	}
+
+	for name, signer := range signers {
+		app := &App{creds: &Credentials{ProjectID: testProjectID}, signer: signer}
+		s, err := app.Auth().CreateCustomToken(context.Background(), "uid1", &Claims{"roles": []string{"admin"}})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		token, err := jws.ParseJWT([]byte(s))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if err := token.Validate(&testKey.PublicKey, crypto.SigningMethodRS256); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if iss, _ := token.Claims().Issuer(); iss != testClientEmail {
+			t.Errorf("%s: expected issuer %s, got %s", name, testClientEmail, iss)
+		}
+	}
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}}
+	if _, err := app.Auth().CreateCustomToken(context.Background(), "uid1", nil); err == nil {
+		t.Error("expected error without a signer")
+	}
+}
+
+func TestCreateCustomTokenWithOptions(t *testing.T) {
+	signer := &rsaSigner{email: testClientEmail, keyID: testKeyID, key: testKey}
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, signer: signer}
+	ctx := context.Background()
+
+	s, err := app.TenantAuth("tenant-1").CreateCustomTokenWithOptions(ctx, "uid1", &CustomTokenOptions{
+		Expires:      10 * time.Minute,
+		IncludeKeyID: true,
+		Headers:      map[string]interface{}{"typ": "JWT"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jws.ParseJWT([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := token.Validate(&testKey.PublicKey, crypto.SigningMethodRS256); err != nil {
+		t.Error(err)
+	}
+	if kid := token.(jws.JWS).Protected().Get("kid"); kid != testKeyID {
+		t.Errorf("expected kid %s, got %v", testKeyID, kid)
+	}
+	if tenant := token.Claims().Get("tenant_id"); tenant != "tenant-1" {
+		t.Errorf("expected tenant tenant-1, got %v", tenant)
+	}
+	iat, _ := token.Claims().IssuedAt()
+	exp, _ := token.Claims().Expiration()
+	if d := exp.Sub(iat); d != 10*time.Minute {
+		t.Errorf("expected 10m expiry, got %s", d)
+	}
+
+	s, err = app.Auth().CreateCustomTokenWithOptions(ctx, "uid1", &CustomTokenOptions{SigningMethod: crypto.SigningMethodRS512})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, err = jws.ParseJWT([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := token.Validate(&testKey.PublicKey, crypto.SigningMethodRS512); err != nil {
+		t.Error(err)
+	}
+
+	invalid := map[string]*CustomTokenOptions{
+		"expiry":  {Expires: 2 * time.Hour},
+		"tenant":  {TenantID: "tenant-2"},
+		"alg":     {Headers: map[string]interface{}{"alg": "none"}},
+		"key id":  {IncludeKeyID: true},
+		"signing": {SigningMethod: crypto.SigningMethodRS512},
+	}
+	iam := &App{creds: &Credentials{ProjectID: testProjectID}, signer: NewIAMSigner(testClientEmail, "")}
+	for name, options := range invalid {
+		auth := app.TenantAuth("tenant-1")
+		if name == "key id" || name == "signing" {
+			auth = iam.Auth()
+		}
+		if _, err := auth.CreateCustomTokenWithOptions(ctx, "uid1", options); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package firebase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/jws"
+)
+
+func TestTokenAccessors(t *testing.T) {
+	authTime := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s := testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", authTime, map[string]interface{}{
+		"roles": []string{"admin"},
+		"firebase": map[string]interface{}{
+			"sign_in_provider": "password",
+			"identities": map[string]interface{}{
+				"email": []string{"email@address"},
+			},
+			"tenant":                   "tenant-1",
+			"sign_in_second_factor":    "phone",
+			"second_factor_identifier": "factor-1",
+		},
+	})
+	j, err := jws.ParseJWT([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &Token{j}
+
+	info, ok := token.Firebase()
+	if !ok {
+		t.Fatal("expected firebase claim")
+	}
+	expected := &FirebaseInfo{
+		SignInProvider:         "password",
+		Identities:             map[string][]string{"email": {"email@address"}},
+		Tenant:                 "tenant-1",
+		SignInSecondFactor:     "phone",
+		SecondFactorIdentifier: "factor-1",
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %+v, got %+v", expected, info)
+	}
+
+	if at, ok := token.AuthTime(); !ok || !at.Equal(authTime) {
+		t.Errorf("expected auth time %v, got %v", authTime, at)
+	}
+	iat, _ := token.IssuedAt()
+	exp, _ := token.Expires()
+	if exp.Sub(iat) != time.Hour {
+		t.Errorf("expected 1 hour between %v and %v", iat, exp)
+	}
+
+	var claims struct {
+		Roles []string `json:"roles"`
+	}
+	if err := token.DecodeClaims(&claims); err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != "admin" {
+		t.Errorf("unexpected roles %v", claims.Roles)
+	}
+}
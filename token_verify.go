@@ -3,6 +3,8 @@ package firebase
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/SermoDigital/jose/crypto"
 	"github.com/SermoDigital/jose/jws"
@@ -10,54 +12,179 @@ import (
 	"golang.org/x/net/context"
 )
 
+const (
+	// Issuer prefix for Firebase Auth ID tokens
+	idTokenIssuerPrefix = "https://securetoken.google.com/"
+)
+
 func (a *Auth) VerifyIDToken(ctx context.Context, token string) (*Token, error) {
-	decodedJWT, err := jws.ParseJWT([]byte(token))
+	return a.verifyToken(ctx, token, "ID Token", a.app.keys, idTokenIssuerPrefix+a.app.creds.ProjectID)
+}
+
+// verifyToken checks the signature of the token against the public keys from
+// the key source and validates its claims for the project and issuer. The kind is
+// used to describe the token in error messages.
+func (a *Auth) verifyToken(ctx context.Context, token, kind string, keySource KeySource, issuer string) (*Token, error) {
+	ctx, err := a.app.context(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	decodedJWT, err := jws.ParseJWT([]byte(token))
+	if err != nil {
+		return nil, malformedError(err)
+	}
+
 	decodedJWS, ok := decodedJWT.(jws.JWS)
 	if !ok {
-		return nil, errors.New("Firebase Auth ID Token cannot be decoded")
+		return nil, fmt.Errorf("%w: %s cannot be decoded", ErrMalformedToken, kind)
+	}
+
+	// the emulator issues unsigned tokens
+	if a.app.IsEmulator() {
+		if err := decodedJWT.Validate(nil, crypto.Unsecured, a.validator(issuer)); err != nil {
+			return nil, verifyError(err)
+		}
+		return a.checkPolicy(&Token{decodedJWT})
 	}
 
 	keys := func(j jws.JWS) ([]interface{}, error) {
 		kid, ok := j.Protected().Get("kid").(string)
 		if !ok {
-			return nil, errors.New("Firebase Auth ID Token has no 'kid' claim")
+			return nil, fmt.Errorf("%w: %s has no 'kid' claim", ErrMalformedToken, kind)
 		}
-		cert, err := certs.Get(ctx, kid)
+		key, err := keySource.PublicKey(ctx, kid)
 		if err != nil {
 			return nil, err
 		}
-		return []interface{}{cert.PublicKey}, nil
+		return []interface{}{key}, nil
 	}
 
 	if err := decodedJWS.VerifyCallback(keys,
 		[]crypto.SigningMethod{crypto.SigningMethodRS256},
 		&jws.SigningOpts{Number: 1, Indices: []int{0}}); err != nil {
-		return nil, err
+		return nil, verifyError(err)
 	}
 
 	ks, _ := keys(decodedJWS)
 	key := ks[0]
-	if err := decodedJWT.Validate(key, crypto.SigningMethodRS256, validator(a.app.creds.ProjectID)); err != nil {
+	if err := decodedJWT.Validate(key, crypto.SigningMethodRS256, a.validator(issuer)); err != nil {
+		return nil, verifyError(err)
+	}
+
+	return a.checkPolicy(&Token{decodedJWT})
+}
+
+// checkPolicy applies the Auth verification options to a validated token.
+func (a *Auth) checkPolicy(t *Token) (*Token, error) {
+	if err := a.verify.validate(t); err != nil {
 		return nil, err
 	}
+	return t, nil
+}
 
-	return &Token{decodedJWT}, nil
+// VerifyIDTokenAndCheckRevoked verifies the ID token like VerifyIDToken and
+// then looks up the user to check that the account has not been disabled and
+// that the token was not issued before the user's tokens were revoked.
+func (a *Auth) VerifyIDTokenAndCheckRevoked(ctx context.Context, token string) (*Token, error) {
+	t, err := a.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = a.app.context(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.lookupUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	if user.ValidSince != "" {
+		validSince, err := strconv.ParseInt(user.ValidSince, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		authTime, ok := t.Claims().GetTime("auth_time")
+		if !ok || authTime.Before(time.Unix(validSince, 0)) {
+			return nil, ErrIDTokenRevoked
+		}
+	}
+
+	return t, nil
+}
+
+type userRecord struct {
+	LocalID    string `json:"localId"`
+	Disabled   bool   `json:"disabled"`
+	ValidSince string `json:"validSince"`
+}
+
+// lookupUser fetches the account the ID token belongs to using the Identity
+// Toolkit accounts:lookup method.
+func (a *Auth) lookupUser(ctx context.Context, token string) (*userRecord, error) {
+	req := struct {
+		IDToken  string `json:"idToken"`
+		TenantID string `json:"tenantId,omitempty"`
+	}{
+		IDToken:  token,
+		TenantID: a.tenantID,
+	}
+	var resp struct {
+		Users []*userRecord `json:"users"`
+	}
+
+	if err := postAuthorizedJSON(ctx, a.app.tokens, a.app.identityToolkitEndpoint("accounts:lookup"), req, &resp); err != nil {
+		var e *IdentityToolkitError
+		if errors.As(err, &e) {
+			switch e.Message {
+			case "USER_DISABLED":
+				return nil, ErrUserDisabled
+			case "USER_NOT_FOUND":
+				return nil, ErrUserNotFound
+			}
+		}
+		return nil, err
+	}
+
+	if len(resp.Users) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return resp.Users[0], nil
 }
 
-func validator(projectID string) *jwt.Validator {
+// validator checks the registered claims for the project and issuer, along
+// with the tenant if the Auth is tenant-scoped.
+func (a *Auth) validator(issuer string) *jwt.Validator {
+	audiences := append([]string{a.app.creds.ProjectID}, a.verify.audiences...)
+
 	v := &jwt.Validator{}
-	v.EXP = acceptableExpSkew
-	v.SetAudience(projectID)
-	v.SetIssuer(fmt.Sprintf("https://securetoken.google.com/%s", projectID))
+	v.EXP = a.verify.clockSkew
+	v.NBF = a.verify.clockSkew
+	v.SetIssuer(issuer)
 	v.Fn = func(claims jwt.Claims) error {
+		aud, _ := claims.Audience()
+		if len(aud) != 1 || !contains(audiences, aud[0]) {
+			return jwt.ErrInvalidAUDClaim
+		}
+		if iat, ok := claims.IssuedAt(); !ok || iat.After(clock.Now().Add(a.verify.clockSkew)) {
+			return jwt.ErrTokenNotYetValid
+		}
 		subject, ok := claims.Subject()
 		if !ok || len(subject) == 0 || len(subject) > 128 {
 			return jwt.ErrInvalidSUBClaim
 		}
+		if a.tenantID != "" {
+			fb, _ := claims.Get("firebase").(map[string]interface{})
+			if tenant, _ := fb["tenant"].(string); tenant != a.tenantID {
+				return ErrInvalidTenant
+			}
+		}
 		return nil
 	}
 	return v
@@ -0,0 +1,217 @@
+package firebase
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/SermoDigital/jose/crypto"
+	"github.com/SermoDigital/jose/jws"
+	"golang.org/x/net/context"
+)
+
+const (
+	testProjectID = "test-project"
+	testKeyID     = "test-key"
+)
+
+var testKey *rsa.PrivateKey
+
+func init() {
+	var err error
+	testKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// testCertServer serves testKey as an x509 certificate in the Google
+// metadata format.
+func testCertServer(t *testing.T) *httptest.Server {
+	body := testCertBody(t)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(body)
+	}))
+}
+
+// testCertBody returns testKey as an x509 certificate in the Google metadata
+// format.
+func testCertBody(t *testing.T) []byte {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &testKey.PublicKey, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(map[string]string{
+		testKeyID: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+	})
+	return body
+}
+
+// testIDToken mints an ID token for the test project signed with testKey.
+func testIDToken(t *testing.T, uid string, authTime time.Time) string {
+	return testToken(t, idTokenIssuerPrefix+testProjectID, uid, authTime, nil)
+}
+
+// testToken mints a token for the test project and issuer signed with testKey.
+// Any extra claims are added to the token.
+func testToken(t *testing.T, issuer, uid string, authTime time.Time, extra map[string]interface{}) string {
+	now := time.Now()
+	claims := jws.Claims{}
+	claims.SetIssuer(issuer)
+	claims.SetAudience(testProjectID)
+	claims.SetSubject(uid)
+	claims.SetIssuedAt(now)
+	claims.SetExpiration(now.Add(time.Hour))
+	claims.Set("auth_time", authTime.Unix())
+	for k, v := range extra {
+		claims.Set(k, v)
+	}
+
+	j := jws.NewJWT(claims, crypto.SigningMethodRS256)
+	j.(jws.JWS).Protected().Set("kid", testKeyID)
+	b, err := j.Serialize(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestVerifyIDTokenTenant(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, keys: NewX509KeySource(certSrv.URL)}
+	ctx := context.Background()
+
+	tenantToken := func(tenant string) string {
+		return testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+			"firebase": map[string]interface{}{"tenant": tenant},
+		})
+	}
+
+	if _, err := app.TenantAuth("tenant-1").VerifyIDToken(ctx, tenantToken("tenant-1")); err != nil {
+		t.Error(err)
+	}
+	if _, err := app.TenantAuth("tenant-1").VerifyIDToken(ctx, tenantToken("tenant-2")); err != ErrInvalidTenant {
+		t.Errorf("expected ErrInvalidTenant, got %v", err)
+	}
+	if _, err := app.TenantAuth("tenant-1").VerifyIDToken(ctx, testIDToken(t, "uid1", time.Now())); err != ErrInvalidTenant {
+		t.Errorf("expected ErrInvalidTenant, got %v", err)
+	}
+	if _, err := app.Auth().VerifyIDToken(ctx, tenantToken("tenant-2")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVerifyIDTokenAndCheckRevoked(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	authTime := time.Now().Add(-10 * time.Minute)
+
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		err      error
+	}{
+		{"valid", http.StatusOK, `{"users":[{"localId":"uid1"}]}`, nil},
+		{"valid since before auth", http.StatusOK, `{"users":[{"localId":"uid1","validSince":"` + unix(authTime.Add(-time.Minute)) + `"}]}`, nil},
+		{"revoked", http.StatusOK, `{"users":[{"localId":"uid1","validSince":"` + unix(authTime.Add(time.Minute)) + `"}]}`, ErrIDTokenRevoked},
+		{"disabled", http.StatusOK, `{"users":[{"localId":"uid1","disabled":true}]}`, ErrUserDisabled},
+		{"disabled error", http.StatusBadRequest, `{"error":{"code":400,"message":"USER_DISABLED"}}`, ErrUserDisabled},
+	}
+
+	for _, test := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/accounts:lookup" || r.URL.Query().Get("key") != "api-key" {
+				t.Errorf("%s: unexpected request %s", test.name, r.URL)
+			}
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.response))
+		}))
+
+		app := &App{
+			creds:              &Credentials{ProjectID: testProjectID},
+			identityToolkitURL: srv.URL,
+			apiKey:             "api-key",
+			keys:               NewX509KeySource(certSrv.URL),
+		}
+
+		_, err := app.Auth().VerifyIDTokenAndCheckRevoked(context.Background(), testIDToken(t, "uid1", authTime))
+		if err != test.err {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.err, err)
+		}
+		srv.Close()
+	}
+}
+
+func unix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func TestVerifyIDTokenErrors(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	auth := (&App{creds: &Credentials{ProjectID: testProjectID}, keys: NewX509KeySource(certSrv.URL)}).Auth()
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"malformed", "a.b", ErrMalformedToken},
+		{"expired", testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}), ErrTokenExpired},
+		{"audience", testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", time.Now(), map[string]interface{}{
+			"aud": "other-project",
+		}), ErrInvalidAudience},
+		{"issuer", testToken(t, idTokenIssuerPrefix+"other-project", "uid1", time.Now(), nil), ErrInvalidIssuer},
+		{"subject", testIDToken(t, "", time.Now()), ErrInvalidSubject},
+	}
+
+	for _, test := range tests {
+		if _, err := auth.VerifyIDToken(ctx, test.token); !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	token := testIDToken(t, "uid1", time.Now())
+	auth.app.keys = newCertificateStore(certSrv.URL)
+	certSrv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	_, err := auth.VerifyIDToken(ctx, token)
+	var keyErr *KeyNotFoundError
+	if !errors.As(err, &keyErr) || keyErr.KeyID != testKeyID {
+		t.Errorf("expected KeyNotFoundError, got %v", err)
+	}
+
+	auth.app.keys = newCertificateStore(certSrv.URL)
+	certSrv.Config.Handler = http.NotFoundHandler()
+	_, err = auth.VerifyIDToken(ctx, token)
+	var fetchErr *CertFetchError
+	if !errors.As(err, &fetchErr) || fetchErr.StatusCode != http.StatusNotFound || !errors.Is(err, ErrCertFetch) {
+		t.Errorf("expected CertFetchError, got %v", err)
+	}
+}
@@ -2,10 +2,19 @@ package firebase
 
 import (
 	"net/http"
+	"time"
 
 	"golang.org/x/net/context"
 )
 
+const (
+	// User-Agent header sent with outbound requests
+	userAgent = "go-firebase/" + Version
+
+	// limit on outbound requests made with the default client
+	defaultHTTPTimeout = 30 * time.Second
+)
+
 // HTTPClient is the context key to use with golang.org/x/net/context's
 // WithValue function to associate an *http.Client value with a context.
 var HTTPClient ContextKey
@@ -52,6 +61,10 @@ func RegisterContextClientFunc(fn ContextClientFunc) {
 	contextClientFuncs = append(contextClientFuncs, fn)
 }
 
+// ContextClient returns the *http.Client set as the HTTPClient context
+// value, or else the one from the first registered func to provide one,
+// or else the client of the App making the call, falling back to
+// http.DefaultClient.
 func ContextClient(ctx context.Context) (*http.Client, error) {
 	if ctx != nil {
 		if hc, ok := ctx.Value(HTTPClient).(*http.Client); ok {
@@ -67,9 +80,84 @@ func ContextClient(ctx context.Context) (*http.Client, error) {
 			return c, nil
 		}
 	}
+	if ctx != nil {
+		if hc, ok := ctx.Value(appClientKey{}).(*http.Client); ok {
+			return hc, nil
+		}
+	}
 	return http.DefaultClient, nil
 }
 
+// requestScopedClient reports whether requests made with the context use a
+// client from a registered ContextClientFunc, such as App Engine's urlfetch
+// client, which stops working when the request the context belongs to ends.
+// Work using such a client can't outlive the caller.
+func requestScopedClient(ctx context.Context) bool {
+	if ctx != nil {
+		if _, ok := ctx.Value(HTTPClient).(*http.Client); ok {
+			return false
+		}
+	}
+	for _, fn := range contextClientFuncs {
+		if c, err := fn(ctx); c != nil || err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// appClientKey is the context key for the *http.Client of the App making
+// a call, used when neither the context nor a registered func provide one.
+type appClientKey struct{}
+
+func withAppClient(ctx context.Context, client *http.Client) context.Context {
+	if client == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, appClientKey{}, client)
+}
+
+// userAgentTransport sets the User-Agent header of requests.
+type userAgentTransport struct {
+	base      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := cloneRequestWithHeader(req, "User-Agent", t.userAgent)
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(r)
+}
+
+// cloneRequestWithHeader returns a copy of the request with its own header,
+// which has the key set to the value. A RoundTripper must not modify the
+// request it's given.
+func cloneRequestWithHeader(req *http.Request, key, value string) *http.Request {
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set(key, value)
+	return r
+}
+
+// newHTTPClient returns a client using the transport, with the default
+// timeout and the library User-Agent.
+func newHTTPClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: &userAgentTransport{
+			base:      transport,
+			userAgent: userAgent,
+		},
+		Timeout: defaultHTTPTimeout,
+	}
+}
+
 func ContextTransport(ctx context.Context) http.RoundTripper {
 	hc, err := ContextClient(ctx)
 	// This is a rare error case (somebody using nil on App Engine).
@@ -79,6 +167,20 @@ func ContextTransport(ctx context.Context) http.RoundTripper {
 	return hc.Transport
 }
 
+// detachedContext carries the values of a context but not its deadline or
+// cancellation, for work that should outlive the request that started it.
+type detachedContext struct {
+	context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
 // ErrorTransport returns the specified error on RoundTrip.
 // This RoundTripper should be used in rare error cases where
 // error handling can be postponed to response handling time.
@@ -0,0 +1,63 @@
+package firebase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+// countingTransport counts the requests made through it.
+type countingTransport struct {
+	n int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.n++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAppHTTPClient(t *testing.T) {
+	var agent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agent = r.Header.Get("User-Agent")
+		w.Write([]byte(`{"idToken":"id-token","refreshToken":"refresh-token","expiresIn":"3600","localId":"uid1"}`))
+	}))
+	defer srv.Close()
+
+	transport := &countingTransport{}
+	app, err := NewApp(
+		WithCredentials(&Credentials{ProjectID: testProjectID}),
+		WithIdentityToolkitURL(srv.URL),
+		WithTransport(transport),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Delete()
+
+	ctx := context.Background()
+	if _, err := app.Auth().SignInWithCustomToken(ctx, "custom-token", "api-key"); err != nil {
+		t.Fatal(err)
+	}
+	if transport.n != 1 {
+		t.Errorf("expected the app transport to be used, got %d requests", transport.n)
+	}
+	if agent != userAgent {
+		t.Errorf("expected User-Agent %s, got %s", userAgent, agent)
+	}
+	if app.client.Timeout != defaultHTTPTimeout {
+		t.Errorf("expected default timeout, got %s", app.client.Timeout)
+	}
+
+	// a client in the context overrides the app's
+	override := &countingTransport{}
+	ctx = context.WithValue(ctx, HTTPClient, &http.Client{Transport: override})
+	if _, err := app.Auth().SignInWithCustomToken(ctx, "custom-token", "api-key"); err != nil {
+		t.Fatal(err)
+	}
+	if transport.n != 1 || override.n != 1 {
+		t.Errorf("expected the context client to be used, got %d and %d requests", transport.n, override.n)
+	}
+}
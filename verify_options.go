@@ -0,0 +1,121 @@
+package firebase
+
+import (
+	"time"
+)
+
+type (
+	// VerifyOption configures how strictly an Auth verifies tokens
+	VerifyOption func(*verifyConfig)
+
+	// ClaimsValidatorFunc is called with each token that passes the built-in
+	// checks. Returning an error rejects the token.
+	ClaimsValidatorFunc func(*Token) error
+
+	verifyConfig struct {
+		clockSkew            time.Duration
+		maxAuthAge           time.Duration
+		requireEmailVerified bool
+		signInProviders      []string
+		audiences            []string
+		claimsFn             ClaimsValidatorFunc
+	}
+)
+
+func defaultVerifyConfig() verifyConfig {
+	return verifyConfig{
+		clockSkew: acceptableExpSkew,
+	}
+}
+
+// VerifyClockSkew sets the leeway allowed when checking the token times
+func VerifyClockSkew(skew time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.clockSkew = skew
+	}
+}
+
+// VerifyMaxAuthAge rejects tokens where the user signed in longer ago than
+// the maximum age, requiring them to re-authenticate
+func VerifyMaxAuthAge(age time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.maxAuthAge = age
+	}
+}
+
+// VerifyEmailVerified rejects tokens where the email address hasn't been
+// verified
+func VerifyEmailVerified() VerifyOption {
+	return func(c *verifyConfig) {
+		c.requireEmailVerified = true
+	}
+}
+
+// VerifySignInProviders only accepts tokens from users who signed in with
+// one of the providers, e.g. "password" or "google.com"
+func VerifySignInProviders(providers ...string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.signInProviders = append(c.signInProviders, providers...)
+	}
+}
+
+// VerifyAudiences accepts tokens for the audiences in addition to the
+// app's project
+func VerifyAudiences(audiences ...string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.audiences = append(c.audiences, audiences...)
+	}
+}
+
+// VerifyClaims adds a function to validate the custom claims
+func VerifyClaims(fn ClaimsValidatorFunc) VerifyOption {
+	return func(c *verifyConfig) {
+		c.claimsFn = fn
+	}
+}
+
+// validate applies the policy checks that go beyond the registered claims.
+func (c *verifyConfig) validate(t *Token) error {
+	now := clock.Now()
+
+	if authTime, ok := t.AuthTime(); ok {
+		if authTime.After(now.Add(c.clockSkew)) {
+			return ErrTokenNotYetValid
+		}
+		if c.maxAuthAge > 0 && now.Sub(authTime) > c.maxAuthAge+c.clockSkew {
+			return ErrAuthTooOld
+		}
+	} else if c.maxAuthAge > 0 {
+		return ErrAuthTooOld
+	}
+
+	if c.requireEmailVerified {
+		if verified, _ := t.IsEmailVerified(); !verified {
+			return ErrEmailNotVerified
+		}
+	}
+
+	if len(c.signInProviders) > 0 {
+		info, _ := t.Firebase()
+		if info == nil || !contains(c.signInProviders, info.SignInProvider) {
+			return ErrSignInProviderNotAllowed
+		}
+	}
+
+	if c.claimsFn != nil {
+		if err := c.claimsFn(t); err != nil {
+			return &ClaimsValidationError{Err: err}
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package firebase
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestVerifyOptions(t *testing.T) {
+	certSrv := testCertServer(t)
+	defer certSrv.Close()
+
+	app := &App{creds: &Credentials{ProjectID: testProjectID}, keys: NewX509KeySource(certSrv.URL)}
+	ctx := context.Background()
+	errBanned := errors.New("banned")
+
+	token := func(authTime time.Time, extra map[string]interface{}) string {
+		return testToken(t, idTokenIssuerPrefix+testProjectID, "uid1", authTime, extra)
+	}
+	password := map[string]interface{}{
+		"email_verified": true,
+		"firebase":       map[string]interface{}{"sign_in_provider": "password"},
+	}
+
+	tests := []struct {
+		name    string
+		options []VerifyOption
+		token   string
+		err     error
+	}{
+		{"defaults", nil, token(time.Now(), nil), nil},
+		{"recent auth", []VerifyOption{VerifyMaxAuthAge(time.Hour)}, token(time.Now().Add(-time.Minute), nil), nil},
+		{"old auth", []VerifyOption{VerifyMaxAuthAge(time.Hour)}, token(time.Now().Add(-2*time.Hour), nil), ErrAuthTooOld},
+		{"future auth", nil, token(time.Now().Add(time.Hour), nil), ErrTokenNotYetValid},
+		{"email verified", []VerifyOption{VerifyEmailVerified()}, token(time.Now(), password), nil},
+		{"email not verified", []VerifyOption{VerifyEmailVerified()}, token(time.Now(), nil), ErrEmailNotVerified},
+		{"provider", []VerifyOption{VerifySignInProviders("google.com", "password")}, token(time.Now(), password), nil},
+		{"other provider", []VerifyOption{VerifySignInProviders("google.com")}, token(time.Now(), password), ErrSignInProviderNotAllowed},
+		{"audience", []VerifyOption{VerifyAudiences("other-project")}, token(time.Now(), map[string]interface{}{"aud": "other-project"}), nil},
+		{"other audience", nil, token(time.Now(), map[string]interface{}{"aud": "other-project"}), ErrInvalidAudience},
+		{"expired within skew", nil, token(time.Now(), map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}), nil},
+		{"expired outside skew", []VerifyOption{VerifyClockSkew(time.Second)}, token(time.Now(), map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}), ErrTokenExpired},
+		{"claims", []VerifyOption{VerifyClaims(func(t *Token) error { return errBanned })}, token(time.Now(), nil), errBanned},
+	}
+
+	for _, test := range tests {
+		_, err := app.Auth(test.options...).VerifyIDToken(ctx, test.token)
+		if test.err == nil && err != nil || !errors.Is(err, test.err) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package firebase
+
+// Version is the version of the library, sent in the User-Agent header. It
+// follows semantic versioning and is bumped in the commit a release is tagged
+// from, so a tag vX.Y.Z always builds with Version "X.Y.Z".
+const Version = "1.1.0"